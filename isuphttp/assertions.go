@@ -0,0 +1,214 @@
+package isuphttp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Verdicts of a response checked by assertions
+const (
+	VerdictUp   = "up"
+	VerdictDown = "down"
+)
+
+// Assertion A check of a response
+// Assert returns the reason the response fails the check, or nil if it passes
+type Assertion interface {
+	Assert(response HTTPResponse) error
+	String() string
+}
+
+// AssertionFailure A failed assertion and the reason it failed
+type AssertionFailure struct {
+	Assertion string
+	Reason    string
+}
+
+// assertion A Assertion made from a description and a check function
+type assertion struct {
+	description string
+	check       func(response HTTPResponse) error
+}
+
+func (a assertion) Assert(response HTTPResponse) error {
+	return a.check(response)
+}
+
+func (a assertion) String() string {
+	return a.description
+}
+
+// SetAssertions Set the assertions that give the response verdict
+func (h HTTPRequest) SetAssertions(assertions ...Assertion) HTTPRequest {
+	h.assertions = append([]Assertion(nil), assertions...)
+	return h
+}
+
+// EvaluateAssertions Check a response and get its verdict and failed assertions
+// A response with a request error is always down
+func EvaluateAssertions(response HTTPResponse, assertions []Assertion) (string, []AssertionFailure) {
+	var failures []AssertionFailure
+
+	if response.Error != "" {
+		failures = append(failures, AssertionFailure{Assertion: "request succeeds", Reason: response.Error})
+	}
+
+	for _, a := range assertions {
+		if err := a.Assert(response); err != nil {
+			failures = append(failures, AssertionFailure{Assertion: a.String(), Reason: err.Error()})
+		}
+	}
+
+	if len(failures) > 0 {
+		return VerdictDown, failures
+	}
+
+	return VerdictUp, nil
+}
+
+// ExpectStatus The status code is one of codes
+func ExpectStatus(codes ...int) Assertion {
+	return assertion{
+		description: fmt.Sprintf("status in %v", codes),
+		check: func(response HTTPResponse) error {
+			for _, code := range codes {
+				if response.StatusCode == code {
+					return nil
+				}
+			}
+
+			return fmt.Errorf("status %d not in %v", response.StatusCode, codes)
+		},
+	}
+}
+
+// ExpectStatusRange The status code is between min and max, inclusive
+func ExpectStatusRange(min int, max int) Assertion {
+	return assertion{
+		description: fmt.Sprintf("status between %d and %d", min, max),
+		check: func(response HTTPResponse) error {
+			if response.StatusCode < min || response.StatusCode > max {
+				return fmt.Errorf("status %d not between %d and %d", response.StatusCode, min, max)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectMaxResponseTime The response time is not longer than max
+func ExpectMaxResponseTime(max time.Duration) Assertion {
+	maxMilliseconds := float64(max) / float64(time.Millisecond)
+
+	return assertion{
+		description: fmt.Sprintf("response time at most %v", max),
+		check: func(response HTTPResponse) error {
+			if response.ResponseTime > maxMilliseconds {
+				return fmt.Errorf("response time %vms is longer than %v", response.ResponseTime, max)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectBodyContains The body contains text
+func ExpectBodyContains(text string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("body contains %q", text),
+		check: func(response HTTPResponse) error {
+			if !strings.Contains(response.Body, text) {
+				return fmt.Errorf("body doesn't contain %q", text)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectBodyNotContains The body doesn't contain text
+func ExpectBodyNotContains(text string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("body doesn't contain %q", text),
+		check: func(response HTTPResponse) error {
+			if strings.Contains(response.Body, text) {
+				return fmt.Errorf("body contains %q", text)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectBodyMatches The body matches the regular expression pattern
+// An invalid pattern fails every response
+func ExpectBodyMatches(pattern string) Assertion {
+	expression, err := regexp.Compile(pattern)
+
+	return assertion{
+		description: fmt.Sprintf("body matches %q", pattern),
+		check: func(response HTTPResponse) error {
+			if err != nil {
+				return fmt.Errorf("invalid pattern: %v", err)
+			}
+
+			if !expression.MatchString(response.Body) {
+				return fmt.Errorf("body doesn't match %q", pattern)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectHeader A value of the header name is value
+func ExpectHeader(name string, value string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("header %s is %q", name, value),
+		check: func(response HTTPResponse) error {
+			values := response.GetHeaderValues(name)
+
+			for _, v := range values {
+				if v == value {
+					return nil
+				}
+			}
+
+			if len(values) == 0 {
+				return fmt.Errorf("header %s not found", name)
+			}
+
+			return fmt.Errorf("header %s is %q", name, strings.Join(values, ", "))
+		},
+	}
+}
+
+// ExpectHeaderExists The header name is in the response
+func ExpectHeaderExists(name string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("header %s exists", name),
+		check: func(response HTTPResponse) error {
+			if len(response.GetHeaderValues(name)) == 0 {
+				return fmt.Errorf("header %s not found", name)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectContentType The response media type is mediaType, parameters like charset are ignored
+func ExpectContentType(mediaType string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("content type is %s", mediaType),
+		check: func(response HTTPResponse) error {
+			if !strings.EqualFold(response.ContentType, mediaType) {
+				return fmt.Errorf("content type is %q", response.ContentType)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package isuphttp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateAssertions(t *testing.T) {
+	response := isuphttp.HTTPResponse{
+		StatusCode:   200,
+		Body:         `{"status":"ok","version":"1.2.3"}`,
+		ResponseTime: 120,
+		ContentType:  isuphttp.ApplicationJSON,
+		Headers:      map[string]interface{}{"Cache-Control": []string{"no-cache"}, "Server": []string{"nginx"}},
+	}
+
+	var tests = []struct {
+		assertion   isuphttp.Assertion
+		expectedUp  bool
+		expectedWhy string
+	}{
+		{isuphttp.ExpectStatus(200, 204), true, ""},
+		{isuphttp.ExpectStatus(500), false, "status 200 not in [500]"},
+		{isuphttp.ExpectStatusRange(200, 299), true, ""},
+		{isuphttp.ExpectStatusRange(300, 399), false, "status 200 not between 300 and 399"},
+		{isuphttp.ExpectMaxResponseTime(time.Second), true, ""},
+		{isuphttp.ExpectMaxResponseTime(100 * time.Millisecond), false, "response time 120ms is longer than 100ms"},
+		{isuphttp.ExpectBodyContains(`"status":"ok"`), true, ""},
+		{isuphttp.ExpectBodyContains("error"), false, `body doesn't contain "error"`},
+		{isuphttp.ExpectBodyNotContains("error"), true, ""},
+		{isuphttp.ExpectBodyNotContains("ok"), false, `body contains "ok"`},
+		{isuphttp.ExpectBodyMatches(`"version":"1\.\d+\.\d+"`), true, ""},
+		{isuphttp.ExpectBodyMatches(`"version":"2\.`), false, `body doesn't match "\"version\":\"2\\."`},
+		{isuphttp.ExpectBodyMatches(`(`), false, "invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{isuphttp.ExpectHeader("server", "nginx"), true, ""},
+		{isuphttp.ExpectHeader("Server", "apache"), false, `header Server is "nginx"`},
+		{isuphttp.ExpectHeader("X-Version", "1"), false, "header X-Version not found"},
+		{isuphttp.ExpectHeaderExists("Cache-Control"), true, ""},
+		{isuphttp.ExpectHeaderExists("Set-Cookie"), false, "header Set-Cookie not found"},
+		{isuphttp.ExpectContentType("application/JSON"), true, ""},
+		{isuphttp.ExpectContentType(isuphttp.ApplicationXML), false, `content type is "application/json"`},
+	}
+
+	for _, test := range tests {
+		verdict, failures := isuphttp.EvaluateAssertions(response, []isuphttp.Assertion{test.assertion})
+
+		if test.expectedUp {
+			assert.Equal(t, isuphttp.VerdictUp, verdict, test.assertion.String())
+			assert.Empty(t, failures)
+			continue
+		}
+
+		assert.Equal(t, isuphttp.VerdictDown, verdict, test.assertion.String())
+		if assert.Len(t, failures, 1) {
+			assert.Equal(t, test.assertion.String(), failures[0].Assertion)
+			assert.Equal(t, test.expectedWhy, failures[0].Reason)
+		}
+	}
+}
+
+func TestEvaluateAssertionsRequestError(t *testing.T) {
+	response := isuphttp.HTTPResponse{StatusCode: isuphttp.StatusTimeout, Error: isuphttp.StatusText(isuphttp.StatusTimeout)}
+
+	verdict, failures := isuphttp.EvaluateAssertions(response, []isuphttp.Assertion{isuphttp.ExpectStatus(200)})
+
+	assert.Equal(t, isuphttp.VerdictDown, verdict)
+	assert.Len(t, failures, 2)
+	assert.Equal(t, isuphttp.StatusText(isuphttp.StatusTimeout), failures[0].Reason)
+}
+
+// The call verdict uses the request assertions
+func TestHTTPCallAssertions(t *testing.T) {
+	var tests = []struct {
+		response        isuphttp.HTTPResponse
+		assertions      []isuphttp.Assertion
+		expectedVerdict string
+		expectedFailed  int
+	}{
+		{isuphttp.HTTPResponse{Method: isuphttp.GET, URL: "localhost:8080/api", StatusCode: 200, Body: "ok"}, nil, "", 0},
+		{isuphttp.HTTPResponse{Method: isuphttp.GET, URL: "localhost:8080/api", StatusCode: 200, Body: "ok"}, []isuphttp.Assertion{isuphttp.ExpectStatus(200), isuphttp.ExpectBodyContains("ok")}, isuphttp.VerdictUp, 0},
+		{isuphttp.HTTPResponse{Method: isuphttp.GET, URL: "localhost:8080/api", StatusCode: 503, Body: "down"}, []isuphttp.Assertion{isuphttp.ExpectStatus(200), isuphttp.ExpectBodyContains("ok")}, isuphttp.VerdictDown, 2},
+	}
+
+	for _, test := range tests {
+		HTTPClient := isuphttp.HTTPClient{}
+		HTTPClient.SetMockEnable(true)
+		HTTPClient.AddMockResponse(test.response, test.response.Method, test.response.URL)
+
+		response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/api").SetAssertions(test.assertions...))
+
+		assert.Equal(t, test.expectedVerdict, response.Verdict)
+		assert.Len(t, response.FailedAssertions, test.expectedFailed)
+	}
+}
@@ -0,0 +1,288 @@
+package isuphttp
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// AuthProvider Authenticate the requests of a call
+// Set it in a request or client with SetAuth, it runs after the request is built
+type AuthProvider interface {
+	Authorize(request *http.Request) error
+}
+
+// AuthChallenger An AuthProvider that answers a 401 Unauthorized response
+// If Challenge returns true the request is authorized and sent again, once
+type AuthChallenger interface {
+	AuthProvider
+	Challenge(response *http.Response) bool
+}
+
+// SetAuth Set the provider that authenticates the request, it replaces the client one
+func (h HTTPRequest) SetAuth(auth AuthProvider) HTTPRequest {
+	h.auth = auth
+	return h
+}
+
+// SetAuth Set the provider that authenticates the calls of requests without one
+func (c *HTTPClient) SetAuth(auth AuthProvider) {
+	c.auth = auth
+}
+
+// authFunc A stateless AuthProvider
+type authFunc func(request *http.Request) error
+
+func (f authFunc) Authorize(request *http.Request) error {
+	return f(request)
+}
+
+// GetBasicAuth Get a provider that sends the username and password in the Authorization header
+func GetBasicAuth(username string, password string) AuthProvider {
+	return authFunc(func(request *http.Request) error {
+		request.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// GetBearerAuth Get a provider that sends a static token in the Authorization header
+func GetBearerAuth(token string) AuthProvider {
+	return authFunc(func(request *http.Request) error {
+		request.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// GetAPIKeyHeaderAuth Get a provider that sends an api key in a header, like X-Api-Key
+func GetAPIKeyHeaderAuth(header string, key string) AuthProvider {
+	return authFunc(func(request *http.Request) error {
+		request.Header.Set(header, key)
+		return nil
+	})
+}
+
+// GetAPIKeyQueryAuth Get a provider that sends an api key in a query parameter, like api_key
+func GetAPIKeyQueryAuth(param string, key string) AuthProvider {
+	return authFunc(func(request *http.Request) error {
+		query := request.URL.Query()
+		query.Set(param, key)
+		request.URL.RawQuery = query.Encode()
+
+		return nil
+	})
+}
+
+// digestAuth HTTP Digest authentication (RFC 7616)
+type digestAuth struct {
+	username string
+	password string
+
+	mu        sync.Mutex
+	challenge map[string]string
+	count     int
+}
+
+// GetDigestAuth Get a provider that answers Digest challenges with a username and password
+// The first call gets the challenge in a 401 response and is sent again, next calls reuse
+// the challenge until the server sends a new one. MD5, SHA-256 and their -sess variants are supported.
+func GetDigestAuth(username string, password string) AuthProvider {
+	return &digestAuth{username: username, password: password}
+}
+
+// Authorize Set the Authorization header if a challenge was received
+func (d *digestAuth) Authorize(request *http.Request) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.challenge == nil {
+		return nil
+	}
+
+	d.count++
+
+	cnonce := make([]byte, 8)
+	if _, err := rand.Read(cnonce); err != nil {
+		return err
+	}
+
+	authorization, err := digestAuthorization(d.challenge, d.username, d.password, request.Method, request.URL.RequestURI(), d.count, hex.EncodeToString(cnonce))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", authorization)
+
+	return nil
+}
+
+// Challenge Keep the Digest challenge of a 401 response
+// It returns false if the challenge isn't Digest or if the refused request already answered it, so the credentials are wrong
+func (d *digestAuth) Challenge(response *http.Response) bool {
+	for _, header := range response.Header.Values("WWW-Authenticate") {
+		scheme, params, _ := strings.Cut(header, " ")
+
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		challenge := parseAuthParams(params)
+
+		if challenge["nonce"] == answeredNonce(response.Request) && !strings.EqualFold(challenge["stale"], "true") {
+			return false
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		// Concurrent calls can get the challenge already kept, its nonce count goes on
+		if d.challenge == nil || d.challenge["nonce"] != challenge["nonce"] {
+			d.count = 0
+		}
+
+		d.challenge = challenge
+
+		return true
+	}
+
+	return false
+}
+
+// answeredNonce Get the nonce of the Digest Authorization header sent by a request, if any
+func answeredNonce(request *http.Request) string {
+	if request == nil {
+		return ""
+	}
+
+	scheme, params, _ := strings.Cut(request.Header.Get("Authorization"), " ")
+
+	if !strings.EqualFold(scheme, "Digest") {
+		return ""
+	}
+
+	return parseAuthParams(params)["nonce"]
+}
+
+// digestAuthorization Get the Authorization header that answers a Digest challenge
+func digestAuthorization(challenge map[string]string, username, password, method, uri string, count int, cnonce string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+
+	digest := func(values ...string) string {
+		h := newHash()
+		h.Write([]byte(strings.Join(values, ":")))
+
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	nc := fmt.Sprintf("%08x", count)
+
+	ha1 := digest(username, realm, password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = digest(ha1, nonce, cnonce)
+	}
+
+	ha2 := digest(method, uri)
+
+	qop := ""
+	if options, ok := challenge["qop"]; ok {
+		for _, option := range strings.Split(options, ",") {
+			if strings.TrimSpace(option) == "auth" {
+				qop = "auth"
+			}
+		}
+
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop %s", options)
+		}
+	}
+
+	var response string
+	if qop == "" {
+		response = digest(ha1, nonce, ha2)
+	} else {
+		response = digest(ha1, nonce, nc, cnonce, qop, ha2)
+	}
+
+	authorization := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		username, realm, nonce, uri, algorithm, response)
+
+	if qop != "" {
+		authorization += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+
+	if opaque, ok := challenge["opaque"]; ok {
+		authorization += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+
+	return authorization, nil
+}
+
+// parseAuthParams Parse the key=value and key="quoted, value" parameters of an authentication header
+func parseAuthParams(header string) map[string]string {
+	params := map[string]string{}
+
+	for header != "" {
+		header = strings.TrimLeft(header, " ,")
+
+		key, rest, found := strings.Cut(header, "=")
+		if !found {
+			break
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+
+		var value strings.Builder
+
+		if strings.HasPrefix(rest, `"`) {
+			index := 1
+
+			for ; index < len(rest) && rest[index] != '"'; index++ {
+				if rest[index] == '\\' && index+1 < len(rest) {
+					index++
+				}
+
+				value.WriteByte(rest[index])
+			}
+
+			if index < len(rest) {
+				index++
+			}
+
+			header = rest[index:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			header = rest[end:]
+		}
+
+		params[key] = value.String()
+	}
+
+	return params
+}
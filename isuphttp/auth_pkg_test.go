@@ -0,0 +1,44 @@
+package isuphttp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	// RFC 2617 section 3.5 example
+	challenge := parseAuthParams(`realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+
+	authorization, err := digestAuthorization(challenge, "Mufasa", "Circle Of Life", "GET", "/dir/index.html", 1, "0a4f113b")
+
+	assert.Nil(t, err)
+	assert.Contains(t, authorization, `response="6629fae49393a05397450978507c4ef1"`)
+	assert.Contains(t, authorization, `qop=auth, nc=00000001, cnonce="0a4f113b"`)
+	assert.Contains(t, authorization, `opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+
+	challenge["algorithm"] = "SHA-512"
+	_, err = digestAuthorization(challenge, "Mufasa", "Circle Of Life", "GET", "/dir/index.html", 1, "0a4f113b")
+	assert.EqualError(t, err, "unsupported digest algorithm SHA-512")
+
+	challenge["algorithm"] = "MD5"
+	challenge["qop"] = "auth-int"
+	_, err = digestAuthorization(challenge, "Mufasa", "Circle Of Life", "GET", "/dir/index.html", 1, "0a4f113b")
+	assert.EqualError(t, err, "unsupported digest qop auth-int")
+}
+
+func TestParseAuthParams(t *testing.T) {
+	var tests = []struct {
+		header   string
+		expected map[string]string
+	}{
+		{`realm="a, b", nonce=abc`, map[string]string{"realm": "a, b", "nonce": "abc"}},
+		{`Realm="say \"hi\"",stale=TRUE`, map[string]string{"realm": `say "hi"`, "stale": "TRUE"}},
+		{`nonce="open`, map[string]string{"nonce": "open"}},
+		{``, map[string]string{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parseAuthParams(test.header))
+	}
+}
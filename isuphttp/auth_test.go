@@ -0,0 +1,352 @@
+package isuphttp_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticAuthProviders(t *testing.T) {
+	var tests = []struct {
+		auth          isuphttp.AuthProvider
+		expectedURL   string
+		expectedName  string
+		expectedValue string
+	}{
+		{isuphttp.GetBasicAuth("user", "pass"), "https://api.local/auth?page=1", "Authorization", "Basic dXNlcjpwYXNz"},
+		{isuphttp.GetBearerAuth("token"), "https://api.local/auth?page=1", "Authorization", "Bearer token"},
+		{isuphttp.GetAPIKeyHeaderAuth("X-Api-Key", "key"), "https://api.local/auth?page=1", "X-Api-Key", "key"},
+		{isuphttp.GetAPIKeyQueryAuth("api_key", "k y"), "https://api.local/auth?api_key=k+y&page=1", "Authorization", ""},
+	}
+
+	for _, test := range tests {
+		transport := isuphttp.GetMockTransport()
+
+		HTTPClient := isuphttp.HTTPClient{}
+		HTTPClient.SetTransport(transport)
+
+		HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/auth").
+			SetQueryParams(map[string]interface{}{"page": 1}).
+			SetAuth(test.auth))
+
+		call := transport.GetCalls()[0]
+
+		assert.Equal(t, test.expectedURL, call.URL)
+		assert.Equal(t, test.expectedValue, call.Headers.Get(test.expectedName))
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.SetAuth(isuphttp.GetBearerAuth("client"))
+
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/a"))
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/b").SetAuth(isuphttp.GetBearerAuth("request")))
+
+	HTTPClient.SetAuth(nil)
+	HTTPClient.SetDefaultRequest(isuphttp.GetHTTPRequest("", "").SetAuth(isuphttp.GetBearerAuth("default")))
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/c"))
+
+	calls := transport.GetCalls()
+
+	assert.Equal(t, "Bearer client", calls[0].Headers.Get("Authorization"))
+	assert.Equal(t, "Bearer request", calls[1].Headers.Get("Authorization"))
+	assert.Equal(t, "Bearer default", calls[2].Headers.Get("Authorization"))
+}
+
+// digestServer A server that checks Digest credentials with MD5 and qop auth
+func digestServer(username string, password string, hits *int32) *httptest.Server {
+	hash := func(values ...string) string {
+		sum := md5.Sum([]byte(strings.Join(values, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+
+		params := map[string]string{}
+		for _, part := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "), ", ") {
+			key, value, _ := strings.Cut(part, "=")
+			params[key] = strings.Trim(value, `"`)
+		}
+
+		ha1 := hash(username, "isup", password)
+		ha2 := hash(r.Method, r.URL.RequestURI())
+		expected := hash(ha1, "n0nce", params["nc"], params["cnonce"], "auth", ha2)
+
+		if params["response"] != expected || params["uri"] != r.URL.RequestURI() || params["opaque"] != "0paque" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="isup", qop="auth,auth-int", nonce="n0nce", opaque="0paque", algorithm=MD5`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		fmt.Fprint(w, "welcome "+params["nc"])
+	}))
+}
+
+func TestDigestAuth(t *testing.T) {
+	var hits int32
+
+	server := digestServer("Mufasa", "Circle Of Life", &hits)
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetAuth(isuphttp.GetDigestAuth("Mufasa", "Circle Of Life"))
+
+	request := isuphttp.GetHTTPRequest(isuphttp.POST, server.URL+"/dir/index.html").
+		SetQueryParams(map[string]interface{}{"a": 1}).
+		SetBody(map[string]interface{}{"name": "isup"})
+
+	response := HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "welcome 00000001", response.Body)
+	assert.Equal(t, int32(2), hits)
+
+	// The challenge is reused by the next calls
+	response = HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "welcome 00000002", response.Body)
+	assert.Equal(t, int32(3), hits)
+
+	// Wrong credentials are sent only once more
+	hits = 0
+	HTTPClient.SetAuth(isuphttp.GetDigestAuth("Mufasa", "wrong"))
+
+	response = HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Equal(t, int32(2), hits)
+
+	response = HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Equal(t, int32(3), hits)
+}
+
+func TestDigestAuthParallelRequests(t *testing.T) {
+	var hits int32
+
+	server := digestServer("Mufasa", "Circle Of Life", &hits)
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetAuth(isuphttp.GetDigestAuth("Mufasa", "Circle Of Life"))
+
+	requests := make([]isuphttp.HTTPRequest, 10)
+	for i := range requests {
+		requests[i] = isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/dir/index.html")
+	}
+
+	// The first calls are all challenged, each one answers the challenge
+	for _, response := range HTTPClient.ParallelRequests(requests) {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var tokens int32
+	var expiresIn int32 = 3600
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, _ := r.BasicAuth()
+
+		if id != "client" || secret != "secret" || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad credentials"}`)
+
+			return
+		}
+
+		token := atomic.AddInt32(&tokens, 1)
+
+		w.Header().Set("Content-Type", isuphttp.ApplicationJSON)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d,"scope":"%s"}`, token, atomic.LoadInt32(&expiresIn), r.PostForm.Get("scope"))
+	}))
+	defer tokenServer.Close()
+
+	var revoked int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == fmt.Sprintf("Bearer token-%d", atomic.LoadInt32(&revoked)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetAuth(isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "secret", "read", "write"))
+
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+
+	// The token is cached
+	assert.Equal(t, "Bearer token-1", HTTPClient.HTTPCall(request).Body)
+	assert.Equal(t, "Bearer token-1", HTTPClient.HTTPCall(request).Body)
+	assert.Equal(t, int32(1), tokens)
+
+	// A refused token is fetched again
+	atomic.StoreInt32(&revoked, 1)
+	response := HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "Bearer token-2", response.Body)
+
+	// A token about to expire is refreshed
+	atomic.StoreInt32(&expiresIn, 5)
+	HTTPClient.SetAuth(isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "secret"))
+
+	assert.Equal(t, "Bearer token-3", HTTPClient.HTTPCall(request).Body)
+	assert.Equal(t, "Bearer token-4", HTTPClient.HTTPCall(request).Body)
+
+	// Token errors fail the call
+	HTTPClient.SetAuth(isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "wrong"))
+
+	response = HTTPClient.HTTPCall(request)
+	assert.Equal(t, isuphttp.StatusAuthFailed, response.StatusCode)
+	assert.Equal(t, "oauth2 token request: 401 invalid_client bad credentials", response.Error)
+}
+
+func TestOAuth2ConcurrentCalls(t *testing.T) {
+	var tokens int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := atomic.AddInt32(&tokens, 1)
+		time.Sleep(100 * time.Millisecond)
+
+		w.Header().Set("Content-Type", isuphttp.ApplicationJSON)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, token)
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetAuth(isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "secret"))
+
+	requests := make([]isuphttp.HTTPRequest, 10)
+	for i := range requests {
+		requests[i] = isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+	}
+
+	// The calls wait for the same token request
+	for _, response := range HTTPClient.ParallelRequests(requests) {
+		assert.Equal(t, "Bearer token-1", response.Body)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokens))
+}
+
+func TestOAuth2CallContext(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer tokenServer.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetAuth(isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "secret"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The token request is cancelled with the call
+	start := time.Now()
+	response := HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, "http://api.local"))
+
+	assert.Equal(t, isuphttp.StatusTimeout, response.StatusCode)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	response = HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, "http://api.local"))
+	assert.Equal(t, isuphttp.StatusCanceled, response.StatusCode)
+}
+
+func TestOAuth2TokenTransport(t *testing.T) {
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", isuphttp.ApplicationJSON)
+		fmt.Fprint(w, `{"access_token":"tls-token","token_type":"bearer"}`)
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	auth := isuphttp.GetOAuth2ClientCredentials(tokenServer.URL, "client", "secret")
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetAuth(auth)
+
+	// The client secret isn't sent to an untrusted token server, even by an insecure call
+	HTTPClient := isuphttp.HTTPClient{}
+	assert.Equal(t, isuphttp.StatusAuthFailed, HTTPClient.HTTPCall(request).StatusCode)
+	assert.Equal(t, isuphttp.StatusAuthFailed, HTTPClient.HTTPCall(request.SetInsecureRequest(true)).StatusCode)
+
+	// The token requests use the provider transport
+	auth.SetTransport(tokenServer.Client().Transport)
+	assert.Equal(t, "Bearer tls-token", HTTPClient.HTTPCall(request).Body)
+}
+
+// onceChallenger Answer the first 401 challenge with a header
+type onceChallenger struct {
+	challenged bool
+}
+
+func (o *onceChallenger) Authorize(request *http.Request) error {
+	if o.challenged {
+		request.Header.Set("X-Auth", "ok")
+	}
+
+	return nil
+}
+
+func (o *onceChallenger) Challenge(response *http.Response) bool {
+	o.challenged = true
+	return true
+}
+
+func TestAuthChallengeBodyReader(t *testing.T) {
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if r.Header.Get("X-Auth") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, server.URL).
+		SetBodyReader(strings.NewReader("payload"), "text/plain").
+		SetAuth(&onceChallenger{}))
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
@@ -0,0 +1,221 @@
+package isuphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"sort"
+)
+
+// Request body encodings
+const (
+	bodyNone = iota
+	bodyJSON
+	bodyForm
+	bodyMultipart
+	bodyRaw
+)
+
+// ErrBodyNotAllowed Error for a body set in a request whose method can't have one
+var ErrBodyNotAllowed = errors.New("body not allowed for method")
+
+// Methods that must not send a body
+var methodsWithoutBody = map[string]bool{
+	HEAD:    true,
+	CONNECT: true,
+	TRACE:   true,
+}
+
+// multipartFile A file part of a multipart body
+type multipartFile struct {
+	fieldName string
+	fileName  string
+	content   []byte
+}
+
+// SetFormBody Set body values sent as application/x-www-form-urlencoded
+// Slice values are sent as repeated fields
+func (h HTTPRequest) SetFormBody(body map[string]interface{}) HTTPRequest {
+	h = h.SetBody(body)
+	h.bodyEncoding = bodyForm
+
+	return h
+}
+
+// SetMultipartBody Set body fields sent as multipart/form-data
+func (h HTTPRequest) SetMultipartBody(body map[string]interface{}) HTTPRequest {
+	h = h.SetBody(body)
+	h.bodyEncoding = bodyMultipart
+
+	return h
+}
+
+// AddMultipartFile Add a file to a multipart/form-data body
+func (h HTTPRequest) AddMultipartFile(fieldName string, fileName string, content []byte) HTTPRequest {
+	h.multipartFiles = append(append([]multipartFile(nil), h.multipartFiles...), multipartFile{fieldName: fieldName, fileName: fileName, content: content})
+	h.bodyEncoding = bodyMultipart
+
+	return h
+}
+
+// SetRawBody Set a pre encoded body, like a XML or SOAP payload
+// If contentType is empty no Content-Type header is set
+func (h HTTPRequest) SetRawBody(body []byte, contentType string) HTTPRequest {
+	h.rawBody = body
+	h.bodyReader = nil
+	h.rawContentType = contentType
+	h.bodyEncoding = bodyRaw
+
+	return h
+}
+
+// SetBodyReader Set a body read from a io.Reader
+// Calls that can send the body more than once, with retries or auth challenges, read it in memory first
+func (h HTTPRequest) SetBodyReader(body io.Reader, contentType string) HTTPRequest {
+	h.rawBody = nil
+	h.bodyReader = body
+	h.rawContentType = contentType
+	h.bodyEncoding = bodyRaw
+
+	return h
+}
+
+// bufferBodyReader Read a body reader in memory, so the body can be sent more than once
+func (h HTTPRequest) bufferBodyReader() (HTTPRequest, error) {
+	if h.bodyReader == nil {
+		return h, nil
+	}
+
+	body, err := io.ReadAll(h.bodyReader)
+
+	if err != nil {
+		return h, err
+	}
+
+	return h.SetRawBody(body, h.rawContentType), nil
+}
+
+// encodeBody Encode the request body and get its Content-Type
+// A request without a body gives a nil reader, so no Content-Length is sent
+func (h HTTPRequest) encodeBody() (io.Reader, string, error) {
+	if h.bodyEncoding == bodyNone {
+		return nil, "", nil
+	}
+
+	if methodsWithoutBody[h.method] {
+		return nil, "", fmt.Errorf("%w %s", ErrBodyNotAllowed, h.method)
+	}
+
+	switch h.bodyEncoding {
+	case bodyForm:
+		return bytes.NewBufferString(formValues(h.body).Encode()), FormURLEncoded, nil
+	case bodyMultipart:
+		return h.encodeMultipartBody()
+	case bodyRaw:
+		if h.bodyReader != nil {
+			return h.bodyReader, h.rawContentType, nil
+		}
+
+		return bytes.NewReader(h.rawBody), h.rawContentType, nil
+	}
+
+	body, err := json.Marshal(h.body)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bytes.NewBuffer(body), ApplicationJSON, nil
+}
+
+// Encode the body fields and files as multipart/form-data
+func (h HTTPRequest) encodeMultipartBody() (io.Reader, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, name := range sortedKeys(h.body) {
+		for _, value := range valueStrings(h.body[name]) {
+			if err := writer.WriteField(name, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	for _, file := range h.multipartFiles {
+		part, err := writer.CreateFormFile(file.fieldName, file.fileName)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		if _, err := part.Write(file.content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body, writer.FormDataContentType(), nil
+}
+
+// Convert a values map to url.Values
+func formValues(values map[string]interface{}) url.Values {
+	form := url.Values{}
+
+	for name, value := range values {
+		form[name] = valueStrings(value)
+	}
+
+	return form
+}
+
+// Convert a value to strings, slices and arrays give one string for each item
+func valueStrings(value interface{}) []string {
+	if value == nil {
+		return []string{""}
+	}
+
+	if isListValue(value) {
+		v := reflect.ValueOf(value)
+		values := make([]string, v.Len())
+
+		for index := range values {
+			values[index] = fmt.Sprintf("%v", v.Index(index).Interface())
+		}
+
+		return values
+	}
+
+	return []string{fmt.Sprintf("%v", value)}
+}
+
+// If the value is a slice or array, except []byte
+func isListValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	v := reflect.ValueOf(value)
+
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// Get the keys of a values map sorted
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,562 @@
+package isuphttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cassette modes
+const (
+	// CassetteRecord makes real calls and records them
+	CassetteRecord = iota
+	// CassetteReplay answers the calls with the recorded responses, without network
+	CassetteReplay
+)
+
+// Value that replaces the redacted secrets
+const cassetteRedacted = "REDACTED"
+
+// Headers redacted by default
+var cassetteRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Error of a replayed call that doesn't match any recorded interaction
+var errNoCassetteInteraction = errors.New("no cassette interaction matches the call")
+
+// Cassette Calls recorded to a file and replayed by a client set with SetCassette
+// In record mode the client makes real calls and each request and response is kept
+// until Save writes them to the file. In replay mode the file responses are given
+// to the calls with the same method and url, in the recorded order, the last one is repeated.
+// Requests are recorded as they are sent, after their auth and signing.
+// Secrets are replaced by REDACTED before being recorded: the Authorization,
+// Proxy-Authorization, Cookie and Set-Cookie headers, the headers and query parameters
+// set by the auth and signer and the ones set by the Redact methods.
+// The body hash of a redacted response is the hash of the recorded body.
+// A redacted query parameter matches any value when replayed.
+type Cassette struct {
+	path             string
+	mode             int
+	mu               sync.Mutex
+	interactions     []cassetteInteraction
+	replayed         []bool
+	redactedHeaders  []string
+	redactedQuery    []string
+	redactedPatterns []*regexp.Regexp
+}
+
+// cassetteFile The content of a cassette file
+type cassetteFile struct {
+	Interactions []cassetteInteraction `json:"interactions"`
+}
+
+// cassetteInteraction A recorded call
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// cassetteRequest A recorded request, as it was sent
+type cassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+
+	// The call url and headers before its auth and signing, and the query parameters
+	// and headers added by them, which are redacted when recorded
+	callURL     string
+	callHeaders http.Header
+	authQuery   []string
+	authHeaders []string
+}
+
+// cassetteResponse A recorded response, the timings are in nanoseconds
+type cassetteResponse struct {
+	StatusCode      int           `json:"status_code"`
+	Error           string        `json:"error,omitempty"`
+	Headers         http.Header   `json:"headers,omitempty"`
+	Body            string        `json:"body,omitempty"`
+	ContentLength   int64         `json:"content_length"`
+	BodySize        int64         `json:"body_size"`
+	BodySHA256      string        `json:"body_sha256,omitempty"`
+	BodyTruncated   bool          `json:"body_truncated,omitempty"`
+	Host            string        `json:"host,omitempty"`
+	FinalURL        string        `json:"final_url,omitempty"`
+	RedirectChain   []RedirectHop `json:"redirect_chain,omitempty"`
+	ResponseTime    float64       `json:"response_time"`
+	DNSLookup       time.Duration `json:"dns_lookup"`
+	Connect         time.Duration `json:"connect"`
+	TLSHandshake    time.Duration `json:"tls_handshake"`
+	TimeToFirstByte time.Duration `json:"time_to_first_byte"`
+	ContentTransfer time.Duration `json:"content_transfer"`
+}
+
+// GetCassette Instantiate a cassette for a file
+// In replay mode the file is read, in record mode it is written by Save
+func GetCassette(path string, mode int) (*Cassette, error) {
+	cassette := &Cassette{
+		path:            path,
+		mode:            mode,
+		redactedHeaders: append([]string(nil), cassetteRedactedHeaders...),
+	}
+
+	switch mode {
+	case CassetteRecord:
+	case CassetteReplay:
+		content, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var file cassetteFile
+		if err := json.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+
+		cassette.interactions = file.Interactions
+		cassette.replayed = make([]bool, len(file.Interactions))
+	default:
+		return nil, fmt.Errorf("invalid cassette mode %d", mode)
+	}
+
+	return cassette, nil
+}
+
+// RedactHeaders Replace the value of request and response headers
+func (c *Cassette) RedactHeaders(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		c.redactedHeaders = append(c.redactedHeaders, http.CanonicalHeaderKey(name))
+	}
+}
+
+// RedactQueryParams Replace the value of url query parameters, like api keys
+func (c *Cassette) RedactQueryParams(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.redactedQuery = append(c.redactedQuery, names...)
+}
+
+// RedactBody Replace the parts of request and response bodies that match a regular expression
+// If the expression has a group only the first group is replaced, like "password":"([^"]*)"
+func (c *Cassette) RedactBody(pattern string) error {
+	compiled, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return fmt.Errorf("invalid cassette redaction %q: %w", pattern, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.redactedPatterns = append(c.redactedPatterns, compiled)
+
+	return nil
+}
+
+// Save Write the recorded calls to the cassette file
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	content, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, append(content, '\n'), 0o644)
+}
+
+// SetCassette Record the client calls to a cassette or replay them from it, nil disables it
+func (c *HTTPClient) SetCassette(cassette *Cassette) {
+	c.cassette = cassette
+}
+
+// call Replay a call, or make it with send and record it
+func (c *Cassette) call(ctx context.Context, request HTTPRequest, send func(context.Context, HTTPRequest) HTTPResponse) HTTPResponse {
+	if c.mode == CassetteReplay {
+		return c.replay(request)
+	}
+
+	// A body reader can only be read once, so it is kept to be sent and recorded
+	request, err := request.bufferBodyReader()
+
+	if err != nil {
+		return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
+	}
+
+	recorded, err := newCassetteRequest(request)
+
+	if err != nil {
+		return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
+	}
+
+	// The call fills the recorded request with the one it sends, after auth and signing
+	response := send(context.WithValue(ctx, cassetteRecorderKey{}, &recorded), request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, c.redactInteraction(cassetteInteraction{
+		Request:  recorded,
+		Response: newCassetteResponse(response),
+	}))
+
+	return response
+}
+
+// replay Get the recorded response of a call
+func (c *Cassette) replay(request HTTPRequest) HTTPResponse {
+	recorded, err := newCassetteRequest(request)
+
+	if err != nil {
+		return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := -1
+
+	for index, interaction := range c.interactions {
+		if interaction.Request.Method != recorded.Method || !cassetteURLMatches(interaction.Request.URL, recorded.URL) {
+			continue
+		}
+
+		if !c.replayed[index] {
+			c.replayed[index] = true
+
+			return interaction.Response.toHTTPResponse(request.method)
+		}
+
+		last = index
+	}
+
+	if last < 0 {
+		return HTTPResponse{Method: request.method, URL: request.url, StatusCode: http.StatusNotFound, Error: errNoCassetteInteraction.Error()}
+	}
+
+	return c.interactions[last].Response.toHTTPResponse(request.method)
+}
+
+// cassetteRecorderKey Context key of the request recorded for a call
+type cassetteRecorderKey struct{}
+
+// recordSentRequest Record the request sent by a call, with the headers and query set by its auth and signer
+// The query parameters and headers added or changed by them, like an api key, are redacted.
+func recordSentRequest(ctx context.Context, goRequest *http.Request) {
+	recorded, ok := ctx.Value(cassetteRecorderKey{}).(*cassetteRequest)
+
+	if !ok {
+		return
+	}
+
+	recorded.authQuery, recorded.authHeaders = nil, nil
+
+	if callURL, err := url.Parse(recorded.callURL); err == nil {
+		callQuery := callURL.Query()
+
+		for name := range goRequest.URL.Query() {
+			if _, ok := callQuery[name]; !ok {
+				recorded.authQuery = append(recorded.authQuery, name)
+			}
+		}
+	}
+
+	for name, values := range goRequest.Header {
+		if callValues, ok := recorded.callHeaders[name]; !ok || strings.Join(callValues, "\n") != strings.Join(values, "\n") {
+			recorded.authHeaders = append(recorded.authHeaders, name)
+		}
+	}
+
+	recorded.Method = goRequest.Method
+	recorded.URL = goRequest.URL.String()
+	recorded.Headers = goRequest.Header.Clone()
+}
+
+// newCassetteRequest Get the request recorded for a call, before its auth and signing
+func newCassetteRequest(request HTTPRequest) (cassetteRequest, error) {
+	goRequest, err := request.ToGoHTTPRequest()
+
+	if err != nil {
+		return cassetteRequest{}, err
+	}
+
+	recorded := cassetteRequest{
+		Method:      goRequest.Method,
+		URL:         goRequest.URL.String(),
+		Headers:     goRequest.Header,
+		callURL:     goRequest.URL.String(),
+		callHeaders: goRequest.Header.Clone(),
+	}
+
+	if goRequest.Body != nil {
+		body, err := io.ReadAll(goRequest.Body)
+		goRequest.Body.Close()
+
+		if err != nil {
+			return cassetteRequest{}, err
+		}
+
+		recorded.Body = string(body)
+	}
+
+	return recorded, nil
+}
+
+// newCassetteResponse Get the recorded values of a response
+func newCassetteResponse(response HTTPResponse) cassetteResponse {
+	headers := http.Header{}
+
+	for name, value := range response.Headers {
+		switch v := value.(type) {
+		case []string:
+			headers[http.CanonicalHeaderKey(name)] = append([]string(nil), v...)
+		default:
+			headers.Set(name, fmt.Sprintf("%v", v))
+		}
+	}
+
+	return cassetteResponse{
+		StatusCode:      response.StatusCode,
+		Error:           response.Error,
+		Headers:         headers,
+		Body:            response.Body,
+		ContentLength:   response.ContentLength,
+		BodySize:        response.BodySize,
+		BodySHA256:      response.BodySHA256,
+		BodyTruncated:   response.BodyTruncated,
+		Host:            response.URL,
+		FinalURL:        response.FinalURL,
+		RedirectChain:   append([]RedirectHop(nil), response.RedirectChain...),
+		ResponseTime:    response.ResponseTime,
+		DNSLookup:       response.DNSLookup,
+		Connect:         response.Connect,
+		TLSHandshake:    response.TLSHandshake,
+		TimeToFirstByte: response.TimeToFirstByte,
+		ContentTransfer: response.ContentTransfer,
+	}
+}
+
+// toHTTPResponse Get the response of a replayed call
+func (r cassetteResponse) toHTTPResponse(method string) HTTPResponse {
+	response := HTTPResponse{
+		URL:             r.Host,
+		Method:          method,
+		StatusCode:      r.StatusCode,
+		Body:            r.Body,
+		ResponseTime:    r.ResponseTime,
+		ContentLength:   r.ContentLength,
+		Error:           r.Error,
+		FinalURL:        r.FinalURL,
+		RedirectChain:   append([]RedirectHop(nil), r.RedirectChain...),
+		BodySize:        r.BodySize,
+		BodySHA256:      r.BodySHA256,
+		BodyTruncated:   r.BodyTruncated,
+		DNSLookup:       r.DNSLookup,
+		Connect:         r.Connect,
+		TLSHandshake:    r.TLSHandshake,
+		TimeToFirstByte: r.TimeToFirstByte,
+		ContentTransfer: r.ContentTransfer,
+	}
+
+	// Failed calls have no response headers
+	if r.Headers == nil {
+		return response
+	}
+
+	response.Headers = make(map[string]interface{}, len(r.Headers))
+
+	for name, values := range r.Headers {
+		response.Headers[name] = append([]string(nil), values...)
+	}
+
+	response.Cookies = (&http.Response{Header: r.Headers}).Cookies()
+	response.ContentType, response.ContentTypeParams = parseContentType(r.Headers.Get("Content-Type"))
+
+	return response
+}
+
+// redactInteraction Replace the secrets of a recorded call
+func (c *Cassette) redactInteraction(interaction cassetteInteraction) cassetteInteraction {
+	query := append(append([]string{}, c.redactedQuery...), interaction.Request.authQuery...)
+	headers := append(append([]string{}, c.redactedHeaders...), interaction.Request.authHeaders...)
+
+	interaction.Request.URL = redactURL(interaction.Request.URL, query)
+	interaction.Request.Headers = redactHeaders(interaction.Request.Headers, headers)
+	interaction.Request.Body = c.redactBody(interaction.Request.Body)
+
+	interaction.Response.FinalURL = redactURL(interaction.Response.FinalURL, query)
+	interaction.Response.Headers = redactHeaders(interaction.Response.Headers, c.redactedHeaders)
+
+	// The hash is of the recorded body, a truncated body redacted has none
+	if body := c.redactBody(interaction.Response.Body); body != interaction.Response.Body {
+		interaction.Response.Body = body
+		interaction.Response.BodySHA256 = ""
+
+		if !interaction.Response.BodyTruncated {
+			interaction.Response.BodySHA256 = sha256Hex([]byte(body))
+		}
+	}
+
+	for index, hop := range interaction.Response.RedirectChain {
+		interaction.Response.RedirectChain[index].URL = redactURL(hop.URL, query)
+		interaction.Response.RedirectChain[index].Location = redactURL(hop.Location, query)
+	}
+
+	return interaction
+}
+
+// redactHeaders Redact the values of the headers with the given names
+func redactHeaders(headers http.Header, names []string) http.Header {
+	if headers == nil {
+		return nil
+	}
+
+	redacted := headers.Clone()
+
+	for _, name := range names {
+		if values, ok := redacted[name]; ok {
+			for index := range values {
+				values[index] = cassetteRedacted
+			}
+		}
+	}
+
+	return redacted
+}
+
+// redactURL Redact the values of the query parameters with the given names
+func redactURL(rawURL string, names []string) string {
+	if len(names) == 0 || rawURL == "" {
+		return rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	changed := false
+
+	for _, name := range names {
+		if values, ok := query[name]; ok {
+			for index := range values {
+				values[index] = cassetteRedacted
+			}
+
+			changed = true
+		}
+	}
+
+	if !changed {
+		return rawURL
+	}
+
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func (c *Cassette) redactBody(body string) string {
+	for _, pattern := range c.redactedPatterns {
+		var redacted bytes.Buffer
+		end := 0
+
+		for _, match := range pattern.FindAllStringSubmatchIndex(body, -1) {
+			start, stop := match[0], match[1]
+
+			if len(match) >= 4 && match[2] >= 0 {
+				start, stop = match[2], match[3]
+			}
+
+			redacted.WriteString(body[end:start])
+			redacted.WriteString(cassetteRedacted)
+			end = stop
+		}
+
+		redacted.WriteString(body[end:])
+		body = redacted.String()
+	}
+
+	return body
+}
+
+// cassetteURLMatches Check if a call url matches a recorded url
+// A redacted query parameter matches any value
+func cassetteURLMatches(recorded string, call string) bool {
+	if recorded == call {
+		return true
+	}
+
+	recordedURL, err := url.Parse(recorded)
+	if err != nil {
+		return false
+	}
+
+	callURL, err := url.Parse(call)
+	if err != nil {
+		return false
+	}
+
+	if recordedURL.Scheme != callURL.Scheme || recordedURL.Host != callURL.Host || recordedURL.Path != callURL.Path {
+		return false
+	}
+
+	recordedQuery, callQuery := recordedURL.Query(), callURL.Query()
+
+	for name := range callQuery {
+		if _, ok := recordedQuery[name]; !ok {
+			return false
+		}
+	}
+
+	for name, values := range recordedQuery {
+		callValues, ok := callQuery[name]
+
+		// The redacted parameters added by the auth or signer aren't in the call
+		if !ok && redactedValues(values) {
+			continue
+		}
+
+		if !ok || len(callValues) != len(values) {
+			return false
+		}
+
+		for index, value := range values {
+			if value != cassetteRedacted && value != callValues[index] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// redactedValues Check if all the values of a query parameter are redacted
+func redactedValues(values []string) bool {
+	for _, value := range values {
+		if value != cassetteRedacted {
+			return false
+		}
+	}
+
+	return true
+}
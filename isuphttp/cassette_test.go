@@ -0,0 +1,244 @@
+package isuphttp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	var counter int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(&counter, 1)
+
+		switch r.URL.Path {
+		case "/users":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":7,"token":"abc123"}`))
+		case "/status":
+			if call%2 == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			w.Write([]byte("status"))
+		case "/old":
+			http.Redirect(w, r, "/status?key="+r.URL.Query().Get("key"), http.StatusFound)
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := isuphttp.GetCassette(path, isuphttp.CassetteRecord)
+	assert.Nil(t, err)
+
+	recorder.RedactQueryParams("key")
+	assert.Nil(t, recorder.RedactBody(`"(?:password|token)":"([^"]*)"`))
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetCassette(recorder)
+
+	requests := []isuphttp.HTTPRequest{
+		isuphttp.GetHTTPRequest(isuphttp.POST, server.URL+"/users").
+			SetAuthorization("Bearer secret").
+			SetBody(map[string]interface{}{"name": "isup", "password": "hunter2"}),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/status"),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/status"),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/old").SetQueryParams(map[string]interface{}{"key": "k1", "page": 2}),
+	}
+
+	recorded := []isuphttp.HTTPResponse{}
+	for _, request := range requests {
+		recorded = append(recorded, HTTPClient.HTTPCall(request))
+	}
+
+	assert.Nil(t, recorder.Save())
+	server.Close()
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	for _, secret := range []string{"Bearer secret", "hunter2", "abc123", "session=secret", "k1"} {
+		assert.NotContains(t, string(content), secret)
+	}
+
+	player, err := isuphttp.GetCassette(path, isuphttp.CassetteReplay)
+	assert.Nil(t, err)
+
+	HTTPClient.SetCassette(player)
+
+	replayed := HTTPClient.HTTPCall(requests[0])
+	assert.Equal(t, http.StatusCreated, replayed.StatusCode)
+	assert.Equal(t, `{"id":7,"token":"REDACTED"}`, replayed.Body)
+	assert.Equal(t, isuphttp.ApplicationJSON, replayed.ContentType)
+	assert.Equal(t, "utf-8", replayed.ContentTypeParams["charset"])
+	assert.Equal(t, "REDACTED", replayed.GetHeader("Set-Cookie"))
+	assert.Equal(t, recorded[0].ResponseTime, replayed.ResponseTime)
+	assert.Equal(t, recorded[0].Connect, replayed.Connect)
+	assert.Equal(t, recorded[0].TimeToFirstByte, replayed.TimeToFirstByte)
+	assert.NotEqual(t, recorded[0].BodySHA256, replayed.BodySHA256)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte(replayed.Body))), replayed.BodySHA256)
+
+	// The responses of a url are replayed in order and the last one is repeated
+	assert.NotEqual(t, recorded[1].StatusCode, recorded[2].StatusCode)
+	assert.Equal(t, recorded[1].StatusCode, HTTPClient.HTTPCall(requests[1]).StatusCode)
+	assert.Equal(t, recorded[2].StatusCode, HTTPClient.HTTPCall(requests[1]).StatusCode)
+	assert.Equal(t, recorded[2].StatusCode, HTTPClient.HTTPCall(requests[1]).StatusCode)
+
+	// A redacted query parameter matches any value
+	replayed = HTTPClient.HTTPCall(requests[3].SetQueryParams(map[string]interface{}{"key": "other", "page": 2}))
+	assert.Equal(t, recorded[3].StatusCode, replayed.StatusCode)
+	assert.Len(t, replayed.RedirectChain, 1)
+	assert.True(t, strings.HasSuffix(replayed.FinalURL, "/status?key=REDACTED"))
+
+	replayed = HTTPClient.HTTPCall(requests[3].SetQueryParams(map[string]interface{}{"key": "other", "page": 3}))
+	assert.Equal(t, http.StatusNotFound, replayed.StatusCode)
+	assert.Equal(t, "no cassette interaction matches the call", replayed.Error)
+}
+
+// challengedQueryAuth Send a new signature in the query when a call is challenged
+type challengedQueryAuth struct {
+	signatures int32
+}
+
+func (a *challengedQueryAuth) Authorize(request *http.Request) error {
+	query := request.URL.Query()
+	query.Set("sig", fmt.Sprintf("secret-sig-%d", atomic.AddInt32(&a.signatures, 1)))
+	request.URL.RawQuery = query.Encode()
+
+	return nil
+}
+
+func (a *challengedQueryAuth) Challenge(response *http.Response) bool {
+	return true
+}
+
+func TestCassetteRecordsSentRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/key":
+			http.Redirect(w, r, "/key/v2?"+r.URL.RawQuery, http.StatusFound)
+		case "/challenged":
+			if r.URL.Query().Get("sig") != "secret-sig-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := isuphttp.GetCassette(path, isuphttp.CassetteRecord)
+	assert.Nil(t, err)
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetCassette(recorder)
+	HTTPClient.SetSigner(isuphttp.HMACSigner{Key: []byte("key")})
+
+	requests := []isuphttp.HTTPRequest{
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/basic").SetAuth(isuphttp.GetBasicAuth("user", "password")),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/key?page=1").SetAuth(isuphttp.GetAPIKeyQueryAuth("api_key", "secret-key")),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/header").SetAuth(isuphttp.GetAPIKeyHeaderAuth("X-Api-Key", "secret-header")),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/aws").SetHeaderValue("X-Trace", "visible").SetSigner(isuphttp.AWSSigV4Signer{
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret-access",
+			SessionToken:    "secret-session",
+			Region:          "us-east-1",
+			Service:         "s3",
+		}),
+		isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/challenged").SetAuth(&challengedQueryAuth{}),
+	}
+
+	for _, request := range requests {
+		assert.Equal(t, http.StatusOK, HTTPClient.HTTPCall(request).StatusCode)
+	}
+
+	assert.Nil(t, recorder.Save())
+	server.Close()
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	// The auth and signer headers are recorded, the secrets redacted
+	assert.Contains(t, string(content), `"Authorization": [`+"\n"+`            "REDACTED"`)
+	assert.Contains(t, string(content), `"X-Api-Key": [`+"\n"+`            "REDACTED"`)
+	assert.Contains(t, string(content), `"X-Amz-Security-Token": [`+"\n"+`            "REDACTED"`)
+	assert.Contains(t, string(content), `"X-Signature"`)
+	assert.Contains(t, string(content), `"X-Trace": [`+"\n"+`            "visible"`)
+	assert.Contains(t, string(content), `/key?api_key=REDACTED\u0026page=1"`)
+	assert.Contains(t, string(content), `/key/v2?api_key=REDACTED\u0026page=1"`)
+	assert.Contains(t, string(content), `/challenged?sig=REDACTED"`)
+
+	for _, secret := range []string{"secret-key", "secret-header", "secret-session", "secret-sig"} {
+		assert.NotContains(t, string(content), secret)
+	}
+
+	player, err := isuphttp.GetCassette(path, isuphttp.CassetteReplay)
+	assert.Nil(t, err)
+
+	HTTPClient.SetCassette(player)
+
+	// A call matches the recording without the query parameters added by its auth
+	assert.Equal(t, http.StatusOK, HTTPClient.HTTPCall(requests[1]).StatusCode)
+	assert.Equal(t, http.StatusNotFound, HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/key?page=1&other=2")).StatusCode)
+}
+
+func TestCassetteRecordsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	transport := isuphttp.GetMockTransport()
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/down", Err: context.DeadlineExceeded})
+
+	recorder, _ := isuphttp.GetCassette(path, isuphttp.CassetteRecord)
+	recorder.RedactHeaders("X-Api-Key")
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.SetCassette(recorder)
+
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/down").SetHeaderValue("X-Api-Key", "secret")
+
+	recorded := HTTPClient.HTTPCall(request)
+	assert.Equal(t, isuphttp.StatusTimeout, recorded.StatusCode)
+	assert.Nil(t, recorder.Save())
+
+	content, _ := os.ReadFile(path)
+	assert.NotContains(t, string(content), "secret")
+
+	player, _ := isuphttp.GetCassette(path, isuphttp.CassetteReplay)
+	HTTPClient.SetCassette(player)
+
+	replayed := HTTPClient.HTTPCall(request)
+	assert.Equal(t, recorded.StatusCode, replayed.StatusCode)
+	assert.Equal(t, recorded.Error, replayed.Error)
+	assert.Len(t, transport.GetCalls(), 1)
+}
+
+func TestGetCassetteErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte("{"), 0o600)
+
+	_, err := isuphttp.GetCassette(filepath.Join(dir, "missing.json"), isuphttp.CassetteReplay)
+	assert.Error(t, err)
+
+	_, err = isuphttp.GetCassette(invalid, isuphttp.CassetteReplay)
+	assert.ErrorContains(t, err, "invalid cassette")
+
+	_, err = isuphttp.GetCassette(invalid, 7)
+	assert.ErrorContains(t, err, "invalid cassette mode 7")
+
+	cassette, _ := isuphttp.GetCassette(invalid, isuphttp.CassetteRecord)
+	assert.ErrorContains(t, cassette.RedactBody("("), "invalid cassette redaction")
+}
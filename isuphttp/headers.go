@@ -4,5 +4,7 @@ package isuphttp
 const (
 	ApplicationJSON = "application/json"
 	ApplicationXML  = "application/xml"
+	FormURLEncoded  = "application/x-www-form-urlencoded"
+	MultipartForm   = "multipart/form-data"
 	Everything      = "*/*"
 )
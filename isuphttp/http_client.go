@@ -1,126 +1,515 @@
 package isuphttp
 
 import (
-	"crypto/tls"
-	"fmt"
-	"net"
+	"context"
+	"io"
 	"net/http"
-	"strings"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
 	"time"
 )
 
+// Default limits for parallel requests
+const (
+	maxConcurrency        = 10
+	maxConcurrencyPerHost = 0
+)
+
 // HTTPClient HTTPClient
+// MaxConcurrency is the max number of requests made at the same time by ParallelRequests, default is 10
+// MaxConcurrencyPerHost is the max number of requests made at the same time to a single host, 0 means no limit
+// The default request values (base url, headers, query parameters, timeout and insecure flag) are used by every call
+// Calls are retried as set by the request or client RetryPolicy
+// Connections are kept in a pool and reused between calls, Close releases the idle ones
+// The TLSConfig set with SetTLSConfig is used by the calls of requests without one
+// Calls can be recorded to a Cassette and replayed from it offline
+// Middlewares added with Use run around every call
+// The AuthProvider set with SetAuth authenticates the calls of requests without one, and the Signer set with SetSigner signs them
 type HTTPClient struct {
-	mockEnable     bool
-	mockResponse   map[string]HTTPResponse
-	defaultRequest HTTPRequest
+	mockEnable            bool
+	mocks                 *mockRegistry
+	defaultRequest        HTTPRequest
+	maxConcurrency        int
+	maxConcurrencyPerHost int
+	transports            *transportPool
+	cookieJar             http.CookieJar
+	retryPolicy           *RetryPolicy
+	transport             http.RoundTripper
+	cassette              *Cassette
+	middlewares           []Middleware
+	auth                  AuthProvider
+	signer                Signer
+	tlsConfig             *TLSConfig
 }
 
 // ParallelRequests Make multiple requests parallelly
+// The responses are returned in the same order of the requests. Every call is
+// bounded by its own request timeout, so a hanging host only holds its own slot.
 func (c HTTPClient) ParallelRequests(requests []HTTPRequest) []HTTPResponse {
+	return c.ParallelRequestsContext(context.Background(), requests)
+}
+
+// ParallelRequestsContext Make multiple requests parallelly with a context
+// When the context is cancelled, the requests that have not started yet
+// return a StatusCanceled response without being sent.
+func (c HTTPClient) ParallelRequestsContext(ctx context.Context, requests []HTTPRequest) []HTTPResponse {
 	responses := make([]HTTPResponse, len(requests))
 
-	// todo: Parallelize :)
+	limit := c.GetMaxConcurrency()
+	if limit > len(requests) {
+		limit = len(requests)
+	}
+
+	slots := make(chan struct{}, limit)
+	hosts := hostSlots{limit: c.GetMaxConcurrencyPerHost(), slots: make(map[string]chan struct{})}
+
+	var wg sync.WaitGroup
+
 	for index, request := range requests {
-		responses[index] = c.HTTPCall(request)
+		wg.Add(1)
+
+		go func(index int, request HTTPRequest) {
+			defer wg.Done()
+
+			// Wait for the host before taking a global slot, so requests to a
+			// busy host don't starve the others
+			release, ok := hosts.acquire(ctx, request.url)
+			if !ok {
+				responses[index] = canceledResponse(request)
+				return
+			}
+			defer release()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				responses[index] = canceledResponse(request)
+				return
+			}
+			defer func() { <-slots }()
+
+			responses[index] = c.HTTPCallContext(ctx, request)
+		}(index, request)
 	}
 
+	wg.Wait()
+
 	return responses
 }
 
+// SetMaxConcurrency Set the max number of parallel requests
+func (c *HTTPClient) SetMaxConcurrency(limit int) {
+	c.maxConcurrency = limit
+}
+
+// GetMaxConcurrency Get the max number of parallel requests
+func (c HTTPClient) GetMaxConcurrency() int {
+	if c.maxConcurrency < 1 {
+		return maxConcurrency
+	}
+
+	return c.maxConcurrency
+}
+
+// SetMaxConcurrencyPerHost Set the max number of parallel requests to a single host, 0 means no limit
+func (c *HTTPClient) SetMaxConcurrencyPerHost(limit int) {
+	c.maxConcurrencyPerHost = limit
+}
+
+// GetMaxConcurrencyPerHost Get the max number of parallel requests to a single host
+func (c HTTPClient) GetMaxConcurrencyPerHost() int {
+	if c.maxConcurrencyPerHost < 1 {
+		return maxConcurrencyPerHost
+	}
+
+	return c.maxConcurrencyPerHost
+}
+
+// hostSlots limits the number of requests running at the same time for each host
+type hostSlots struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+// acquire waits for a free slot for the request host and returns the function that releases it
+// It returns false if the context is done before a slot is free
+func (h *hostSlots) acquire(ctx context.Context, requestURL string) (func(), bool) {
+	if h.limit < 1 {
+		return func() {}, true
+	}
+
+	host := requestHost(requestURL)
+
+	h.mu.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.limit)
+		h.slots[host] = slots
+	}
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// Return the host of a request url, or the url itself if it can't be parsed
+func requestHost(requestURL string) string {
+	parsed, err := url.Parse(requestURL)
+
+	if err != nil || parsed.Host == "" {
+		return requestURL
+	}
+
+	return parsed.Host
+}
+
 // HTTPCall Make a http call
 func (c HTTPClient) HTTPCall(request HTTPRequest) HTTPResponse {
+	return c.HTTPCallContext(context.Background(), request)
+}
+
+// HTTPCallContext Make a http call with a context
+// If the context is cancelled the response status is StatusCanceled
+func (c HTTPClient) HTTPCallContext(ctx context.Context, request HTTPRequest) HTTPResponse {
+	// A call with a context already done isn't sent
+	if err := ctx.Err(); err != nil {
+		return contextDoneResponse(request, err)
+	}
+
+	return c.chain(c.handle)(ctx, request.withDefaults(c.defaultRequest))
+}
+
+// handle Make a call with its retries and evaluate its assertions
+func (c HTTPClient) handle(ctx context.Context, request HTTPRequest) HTTPResponse {
+	var response HTTPResponse
+
+	switch {
+	case request.retryPolicy != nil:
+		response = c.retryCall(ctx, request, *request.retryPolicy)
+	case c.retryPolicy != nil:
+		response = c.retryCall(ctx, request, *c.retryPolicy)
+	default:
+		response = c.call(ctx, request)
+	}
+
+	if len(request.assertions) > 0 {
+		response.Verdict, response.FailedAssertions = EvaluateAssertions(response, request.assertions)
+	}
+
+	return response
+}
+
+// call Make a single attempt of a call, recording or replaying it if a cassette is set
+func (c HTTPClient) call(ctx context.Context, request HTTPRequest) HTTPResponse {
+	if c.cassette != nil {
+		return c.cassette.call(ctx, request, c.send)
+	}
+
+	return c.send(ctx, request)
+}
+
+// send Make a call, or get its mock response if mocks are enabled
+func (c HTTPClient) send(ctx context.Context, request HTTPRequest) HTTPResponse {
 	if c.mockEnable {
-		return c.GetMockResponse(request.method, request.url)
+		return c.mockCall(ctx, request)
 	}
 
-	return c.httpRequest(request)
+	return c.httpRequest(ctx, request)
+}
+
+// SetDefaultRequest Set the default values used by every call
+// The request url, if any, is the base url for relative request urls
+func (c *HTTPClient) SetDefaultRequest(defaults HTTPRequest) {
+	c.defaultRequest = defaults
 }
 
-func (c HTTPClient) httpRequest(request HTTPRequest) HTTPResponse {
+func (c HTTPClient) httpRequest(ctx context.Context, request HTTPRequest) HTTPResponse {
+
+	trace := &requestTrace{}
+	traceCtx := httptrace.WithClientTrace(ctx, trace.clientTrace())
+
+	auth := request.auth
+	if auth == nil {
+		auth = c.auth
+	}
+
+	// A challenged request is sent again, so its body reader is kept in memory
+	if _, ok := auth.(AuthChallenger); ok {
+		var err error
+		if request, err = request.bufferBodyReader(); err != nil {
+			return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
+		}
+	}
 
 	// request configuration
-	goRequest, err := request.ToGoHTTPRequest()
+	goRequest, errorResponse := c.buildRequest(traceCtx, request, auth)
+
+	if goRequest == nil {
+		return errorResponse
+	}
+
+	redirects := &redirectRecorder{request: request}
+
+	client, release, err := c.getHTTPClient(request)
 
 	if err != nil {
-		fmt.Println(err)
-		return HTTPResponse{StatusCode: 0}
+		return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
 	}
 
+	defer release()
+
+	client.CheckRedirect = redirects.checkRedirect
+
+	recordSentRequest(ctx, goRequest)
+
 	// Make request
 	start := time.Now()
 
-	response, err := c.getHTTPClient(request).Do(goRequest)
+	response, err := client.Do(goRequest)
+
+	// A 401 challenge, like the Digest one, is answered sending the request again
+	if challenger, ok := auth.(AuthChallenger); ok && err == nil && response.StatusCode == http.StatusUnauthorized && challenger.Challenge(response) {
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+
+		if goRequest, errorResponse = c.buildRequest(traceCtx, request, auth); goRequest == nil {
+			return errorResponse
+		}
+
+		recordSentRequest(ctx, goRequest)
+
+		response, err = client.Do(goRequest)
+	}
 
 	elapsed := time.Since(start)
 
 	if err != nil {
-		return c.handleRequestError(err)
+		errorResponse := c.handleRequestError(err)
+		errorResponse.RedirectChain = redirects.chain
+
+		return errorResponse
 	}
 
 	defer response.Body.Close()
 
 	// Process response
 
-	returnresponse := GetHTTPResponse(response)
+	returnresponse := readHTTPResponse(response, request.maxBodySize, request.hashBodyOnly)
+
+	returnresponse.RedirectChain = redirects.chain
+
+	trace.fill(&returnresponse, time.Now())
 
 	returnresponse.ResponseTime = float64(elapsed.Nanoseconds() / 1000000.0)
 
 	return returnresponse
 }
 
-func (c HTTPClient) handleRequestError(err error) HTTPResponse {
-	if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
-		return HTTPResponse{Error: StatusText(StatusTimeout), StatusCode: StatusTimeout}
+// buildRequest Get the go request of a call authorized by auth and signed, or the error response if it can't be built
+func (c HTTPClient) buildRequest(ctx context.Context, request HTTPRequest, auth AuthProvider) (*http.Request, HTTPResponse) {
+	goRequest, err := request.ToGoHTTPRequestContext(ctx)
+
+	if err != nil {
+		return nil, HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
 	}
 
-	if strings.Contains(err.Error(), "TLS handshake error") {
-		return HTTPResponse{Error: StatusText(StatusInvalidCert), StatusCode: StatusInvalidCert}
+	if auth != nil {
+		if err := auth.Authorize(goRequest); err != nil {
+			// A call cancelled or timed out while its auth fetched a token didn't fail its auth
+			if ctx.Err() != nil {
+				status := requestErrorStatus(ctx.Err())
+				return nil, HTTPResponse{Method: request.method, URL: request.url, Error: StatusText(status), StatusCode: status}
+			}
+
+			return nil, HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusAuthFailed}
+		}
+	}
+
+	signer := request.signer
+	if signer == nil {
+		signer = c.signer
+	}
+
+	if signer != nil {
+		if err := signRequest(signer, goRequest); err != nil {
+			return nil, HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusAuthFailed}
+		}
+	}
+
+	return goRequest, HTTPResponse{}
+}
+
+func (c HTTPClient) handleRequestError(err error) HTTPResponse {
+	if status := requestErrorStatus(err); status != 0 {
+		return HTTPResponse{Error: StatusText(status), StatusCode: status}
 	}
 
 	return HTTPResponse{Error: err.Error()}
 }
 
-func (c HTTPClient) getHTTPClient(request HTTPRequest) *http.Client {
-	timeout := time.Duration(request.GetTimeOut()) * time.Millisecond
+// Response for a request cancelled before it was sent
+func canceledResponse(request HTTPRequest) HTTPResponse {
+	return HTTPResponse{Method: request.method, URL: request.url, Error: StatusText(StatusCanceled), StatusCode: StatusCanceled}
+}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: request.GetInsecureRequest()},
-		Dial: (&net.Dialer{
-			Timeout:   timeout,
-			KeepAlive: timeout,
-		}).Dial,
-		TLSHandshakeTimeout:   timeout,
-		ResponseHeaderTimeout: timeout,
-		ExpectContinueTimeout: timeout,
+// Response for a request stopped by its context, a passed deadline is a timeout
+func contextDoneResponse(request HTTPRequest, err error) HTTPResponse {
+	if err == context.DeadlineExceeded {
+		return HTTPResponse{Method: request.method, URL: request.url, Error: StatusText(StatusTimeout), StatusCode: StatusTimeout}
+	}
+
+	return canceledResponse(request)
+}
+
+// getHTTPClient Get the http.Client of a call and the function that releases its pooled transport when the call ends
+func (c HTTPClient) getHTTPClient(request HTTPRequest) (*http.Client, func(), error) {
+	var transport http.RoundTripper = c.transport
+	release := func() {}
+
+	if transport == nil {
+		if request.tlsConfig == nil {
+			request.tlsConfig = c.tlsConfig
+		}
+
+		pooled, releasePooled, err := c.getTransportPool().get(request)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		transport, release = pooled, releasePooled
 	}
 
 	return &http.Client{
-		Transport: tr,
-		Timeout:   timeout,
+		Transport: transport,
+		Timeout:   time.Duration(request.GetTimeOut()) * time.Millisecond,
+		Jar:       c.cookieJar,
+	}, release, nil
+}
+
+// SetCookieJar Set the cookie jar used to keep the cookies between calls
+// A jar from net/http/cookiejar sends the session cookies back to the same domain
+func (c *HTTPClient) SetCookieJar(jar http.CookieJar) {
+	c.cookieJar = jar
+}
+
+// SetIdleConnections Set the idle connections limits of the client pooled transports
+// maxIdle and maxIdlePerHost are the max number of idle connections kept, idleTimeout is how long they are kept
+func (c *HTTPClient) SetIdleConnections(maxIdle int, maxIdlePerHost int, idleTimeout time.Duration) {
+	if c.transports != nil {
+		c.transports.close()
 	}
+
+	c.transports = newTransportPool(maxIdle, maxIdlePerHost, idleTimeout)
+}
+
+// Close Close the idle connections kept by the client, the calls in progress keep theirs until they end
+// Clients without idle connections settings share the default pool, so closing one closes the idle connections of all of them
+func (c *HTTPClient) Close() {
+	c.getTransportPool().close()
+}
+
+// Get the client transport pool, clients without idle connections settings share the default one
+func (c HTTPClient) getTransportPool() *transportPool {
+	if c.transports == nil {
+		return defaultTransportPool
+	}
+
+	return c.transports
 }
 
 // AddMockResponse Add a mock response for a api call
 func (c *HTTPClient) AddMockResponse(expectedResponse HTTPResponse, apiMethod string, apiURL string) {
-	if c.mockResponse == nil {
-		c.mockResponse = make(map[string]HTTPResponse)
-	}
+	c.getMockRegistry().add(MockRule{Method: apiMethod, URL: apiURL, Response: expectedResponse}, true)
+}
 
-	c.mockResponse[apiMethod+"-"+apiURL] = expectedResponse
+// AddMockRule Add a rule that gives a mock response to the api calls it matches
+func (c *HTTPClient) AddMockRule(rule MockRule) error {
+	return c.getMockRegistry().add(rule, false)
 }
 
 // GetMockResponse Get a mock response for a api call
+// It only looks the response up: no call is recorded and the response sequences don't move
 func (c *HTTPClient) GetMockResponse(apiMethod string, apiURL string) HTTPResponse {
-	if c.mockResponse == nil {
-		return HTTPResponse{Method: apiMethod, URL: apiURL, StatusCode: 404}
+	if response, ok := c.mocks.lookup(newMockRequest(GetHTTPRequest(apiMethod, apiURL))); ok {
+		return response
+	}
+
+	return HTTPResponse{Method: apiMethod, URL: apiURL, StatusCode: 404}
+}
+
+// mockCall Get the mock response for a request
+// The rule delay is waited and the rule error is handled as a request error
+func (c HTTPClient) mockCall(ctx context.Context, request HTTPRequest) HTTPResponse {
+	answer, matched, strict := c.mocks.respond(newMockRequest(request))
+
+	if !matched {
+		response := HTTPResponse{Method: request.method, URL: request.url, StatusCode: 404}
+
+		if strict {
+			response.Error = errNoMockRule.Error()
+		}
+
+		return response
+	}
+
+	// The delay is cut by the request timeout, like a slow server
+	if timeOut := request.GetTimeOut(); timeOut > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeOut)*time.Millisecond)
+		defer cancel()
 	}
 
-	if _, ok := c.mockResponse[apiMethod+"-"+apiURL]; !ok {
-		return HTTPResponse{Method: apiMethod, URL: apiURL, StatusCode: 404}
+	if err := answer.wait(ctx); err != nil {
+		return c.handleRequestError(err)
+	}
+
+	if answer.err != nil {
+		return c.handleRequestError(answer.err)
+	}
+
+	return answer.response
+}
+
+// SetMockStrict Make calls that don't match a mock rule fail the test t, nil disables it
+func (c *HTTPClient) SetMockStrict(t TestingT) {
+	c.getMockRegistry().setStrict(t)
+}
+
+// GetMockCalls Get the calls received by the mock, in order
+func (c HTTPClient) GetMockCalls() []MockCall {
+	return c.mocks.getCalls()
+}
+
+// CountMockCalls Count the calls received by the mock that match a rule
+// The rule response is ignored, the matchers can check the calls arguments
+func (c HTTPClient) CountMockCalls(rule MockRule) int {
+	return c.mocks.countCalls(rule)
+}
+
+// AssertMockCalls Check that the mock received times calls that match a rule, failing the test t if not
+func (c HTTPClient) AssertMockCalls(t TestingT, rule MockRule, times int) bool {
+	return c.mocks.assertCalls(t, rule, times)
+}
+
+// ResetMockCalls Forget the calls received by the mock and restart the response sequences
+func (c HTTPClient) ResetMockCalls() {
+	c.mocks.resetCalls()
+}
+
+// Get the client mock rules, creating them if needed
+func (c *HTTPClient) getMockRegistry() *mockRegistry {
+	if c.mocks == nil {
+		c.mocks = &mockRegistry{}
 	}
 
-	return c.mockResponse[apiMethod+"-"+apiURL]
+	return c.mocks
 }
 
 // SetMockEnable Enable or disable mock api call
@@ -1,8 +1,19 @@
 package isuphttp_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/psenna/isup-http-client/isuphttp"
 	"github.com/stretchr/testify/assert"
@@ -118,5 +129,709 @@ func TestGetResponseFromApi(t *testing.T) {
 	assert.Equal(t, http.StatusOK, response.StatusCode)
 }
 
-// Todo: Invalid cert test
-// https://github.com/golang/go/blob/968e18eebd736870a1e3bf06d941dc06e7b20688/src/net/http/client_test.go#L845
+// Parallel requests run at the same time and keep the requests order
+func TestParallelRequestsConcurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	requests := make([]isuphttp.HTTPRequest, 5)
+	for index := range requests {
+		requests[index] = isuphttp.GetHTTPRequest(isuphttp.GET, fmt.Sprintf("%s/%d", server.URL, index))
+	}
+
+	start := time.Now()
+	responses := HTTPClient.ParallelRequests(requests)
+	elapsed := time.Since(start)
+
+	assert.Less(t, elapsed, 600*time.Millisecond)
+
+	for index, response := range responses {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, fmt.Sprintf("/%d", index), response.Body)
+	}
+}
+
+// Parallel requests respect the global and per host limits
+func TestParallelRequestsMaxConcurrency(t *testing.T) {
+	var tests = []struct {
+		maxConcurrency        int
+		maxConcurrencyPerHost int
+		expectedMax           int32
+	}{
+		{1, 0, 1},
+		{3, 0, 3},
+		{5, 2, 2},
+	}
+
+	for _, test := range tests {
+		var running, maxRunning int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+
+			for {
+				old := atomic.LoadInt32(&maxRunning)
+				if current <= old || atomic.CompareAndSwapInt32(&maxRunning, old, current) {
+					break
+				}
+			}
+
+			time.Sleep(50 * time.Millisecond)
+		}))
+
+		HTTPClient := isuphttp.HTTPClient{}
+		HTTPClient.SetMaxConcurrency(test.maxConcurrency)
+		HTTPClient.SetMaxConcurrencyPerHost(test.maxConcurrencyPerHost)
+
+		requests := make([]isuphttp.HTTPRequest, 8)
+		for index := range requests {
+			requests[index] = isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+		}
+
+		HTTPClient.ParallelRequests(requests)
+
+		server.Close()
+
+		assert.Equal(t, test.expectedMax, atomic.LoadInt32(&maxRunning))
+	}
+}
+
+// A hanging host don't hold the batch longer than its own timeout
+func TestParallelRequestsHangingHost(t *testing.T) {
+	hanging := make(chan struct{})
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hanging
+	}))
+	defer slowServer.Close()
+	defer close(hanging)
+
+	fastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fastServer.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	requests := []isuphttp.HTTPRequest{
+		isuphttp.GetHTTPRequest(isuphttp.GET, slowServer.URL).SetTimeOut(200),
+		isuphttp.GetHTTPRequest(isuphttp.GET, fastServer.URL),
+	}
+
+	start := time.Now()
+	responses := HTTPClient.ParallelRequests(requests)
+	elapsed := time.Since(start)
+
+	assert.Less(t, elapsed, time.Second)
+	assert.Equal(t, isuphttp.StatusTimeout, responses[0].StatusCode)
+	assert.Equal(t, http.StatusOK, responses[1].StatusCode)
+}
+
+// Cancel a call in flight
+func TestHTTPCallContextCanceled(t *testing.T) {
+	hanging := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hanging:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hanging)
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	response := HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, server.URL))
+
+	assert.Equal(t, isuphttp.StatusCanceled, response.StatusCode)
+	assert.Equal(t, isuphttp.StatusText(isuphttp.StatusCanceled), response.Error)
+}
+
+// A context deadline is reported as a timeout
+func TestHTTPCallContextDeadline(t *testing.T) {
+	hanging := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hanging:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hanging)
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	response := HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, server.URL))
+
+	assert.Equal(t, isuphttp.StatusTimeout, response.StatusCode)
+}
+
+// A call with a passed deadline is a timeout and isn't sent
+func TestHTTPCallContextDeadlinePassed(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	response := HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, server.URL))
+
+	assert.Equal(t, isuphttp.StatusTimeout, response.StatusCode)
+	assert.Equal(t, isuphttp.StatusText(isuphttp.StatusTimeout), response.Error)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+// Cancelling a batch don't start the pending requests
+func TestParallelRequestsContextCanceled(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMaxConcurrency(1)
+
+	requests := make([]isuphttp.HTTPRequest, 5)
+	for index := range requests {
+		requests[index] = isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	responses := HTTPClient.ParallelRequestsContext(ctx, requests)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	for _, response := range responses {
+		assert.Equal(t, isuphttp.StatusCanceled, response.StatusCode)
+	}
+}
+
+// Classify request errors from real connections
+func TestHTTPCallErrorStatus(t *testing.T) {
+	closedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	// Server that answers a TLS client hello with garbage
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("not a tls server\n"))
+			conn.Close()
+		}
+	}()
+
+	var tests = []struct {
+		apiURL         string
+		expectedStatus int
+	}{
+		{closedServer.URL, isuphttp.StatusConnectionRefused},
+		{tlsServer.URL, isuphttp.StatusUnknownAuthority},
+		{"https://" + listener.Addr().String(), isuphttp.StatusTLSHandshake},
+		{"http://isup.invalid/", isuphttp.StatusDNSFailure},
+	}
+
+	for _, test := range tests {
+		HTTPClient := isuphttp.HTTPClient{}
+
+		response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, test.apiURL))
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode, test.apiURL)
+		assert.Equal(t, isuphttp.StatusText(test.expectedStatus), response.Error)
+	}
+}
+
+// Get the time of each phase of a call
+func TestHTTPCallPhaseTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "first part ")
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, "second part")
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetInsecureRequest(true))
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "first part second part", response.Body)
+	assert.Zero(t, response.DNSLookup)
+	assert.Greater(t, response.Connect, time.Duration(0))
+	assert.Greater(t, response.TLSHandshake, time.Duration(0))
+	assert.GreaterOrEqual(t, response.TimeToFirstByte, 100*time.Millisecond)
+	assert.GreaterOrEqual(t, response.ContentTransfer, 50*time.Millisecond)
+}
+
+// Reuse connections between calls until the client is closed
+func TestHTTPCallConnectionReuse(t *testing.T) {
+	var connections int32
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetIdleConnections(10, 2, time.Minute)
+
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+
+	first := HTTPClient.HTTPCall(request)
+	second := HTTPClient.HTTPCall(request.SetTimeOut(1500))
+
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Greater(t, first.Connect, time.Duration(0))
+	assert.Zero(t, second.Connect)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	HTTPClient.Close()
+
+	third := HTTPClient.HTTPCall(request)
+
+	assert.Equal(t, http.StatusOK, third.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+// Closing a client sharing the default pool closes its idle connections, the calls in progress keep theirs
+func TestHTTPClientCloseSharedPool(t *testing.T) {
+	var connections int32
+
+	started := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	first := isuphttp.HTTPClient{}
+	second := isuphttp.HTTPClient{}
+
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+
+	assert.Equal(t, http.StatusOK, first.HTTPCall(request).StatusCode)
+
+	// The idle connection is reused until the pool is closed
+	response := first.HTTPCall(request)
+	assert.Zero(t, response.Connect)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	done := make(chan isuphttp.HTTPResponse)
+	go func() {
+		done <- first.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/slow"))
+	}()
+
+	<-started
+	second.Close()
+
+	assert.Equal(t, http.StatusOK, (<-done).StatusCode)
+
+	response = second.HTTPCall(request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.NotZero(t, response.Connect)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+// Use the client default request values in every call
+func TestHTTPCallDefaultRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.URL.Query().Get("key"), r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetDefaultRequest(
+		isuphttp.GetHTTPRequest("", server.URL+"/api").
+			SetAuthorization("Bearer default").
+			SetQueryParams(map[string]interface{}{"key": "default"}),
+	)
+
+	var tests = []struct {
+		request      isuphttp.HTTPRequest
+		expectedBody string
+	}{
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "/status"), "/api/status default Bearer default"},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "status").SetAuthorization("Bearer mine"), "/api/status default Bearer mine"},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/health").SetQueryParams(map[string]interface{}{"key": "mine"}), "/health mine Bearer default"},
+	}
+
+	for _, test := range tests {
+		response := HTTPClient.HTTPCall(test.request)
+
+		assert.Equal(t, test.expectedBody, response.Body)
+	}
+}
+
+// Keep the session cookies between calls with a cookie jar
+func TestHTTPCallCookieJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			return
+		}
+
+		session, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, session.Value)
+	}))
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/me"))
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	HTTPClient.SetCookieJar(jar)
+
+	login := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, server.URL+"/login"))
+	if assert.Len(t, login.Cookies, 1) {
+		assert.Equal(t, "session", login.Cookies[0].Name)
+		assert.Equal(t, "abc", login.Cookies[0].Value)
+	}
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/me"))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "abc", response.Body)
+}
+
+// Send no body and no Content-Length when the request has no body
+func TestHTTPCallWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%q %q", r.Header.Get("Content-Length"), body)
+	}))
+	defer server.Close()
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	for _, method := range []string{isuphttp.GET, isuphttp.DELETE} {
+		response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(method, server.URL))
+
+		assert.Equal(t, `"" ""`, response.Body, method)
+	}
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.HEAD, server.URL).SetBody(map[string]interface{}{"a": 1}))
+
+	assert.Equal(t, isuphttp.StatusInvalidRequest, response.StatusCode)
+	assert.Contains(t, response.Error, isuphttp.ErrBodyNotAllowed.Error())
+}
+
+// Retry failed calls as set by the retry policy
+func TestHTTPCallRetryPolicy(t *testing.T) {
+	var tests = []struct {
+		failures         int32
+		failureStatus    int
+		maxAttempts      int
+		clientPolicy     bool
+		expectedStatus   int
+		expectedAttempts []int
+	}{
+		{0, http.StatusServiceUnavailable, 3, false, http.StatusOK, []int{http.StatusOK}},
+		{2, http.StatusServiceUnavailable, 3, false, http.StatusOK, []int{503, 503, 200}},
+		{2, http.StatusBadGateway, 3, true, http.StatusOK, []int{502, 502, 200}},
+		{5, http.StatusServiceUnavailable, 3, false, http.StatusServiceUnavailable, []int{503, 503, 503}},
+		{5, http.StatusNotFound, 3, false, http.StatusNotFound, []int{404}},
+	}
+
+	for _, test := range tests {
+		var calls int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= test.failures {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(test.failureStatus)
+			}
+		}))
+
+		policy := isuphttp.GetDefaultRetryPolicy()
+		policy.MaxAttempts = test.maxAttempts
+		policy.BaseBackoff = time.Millisecond
+
+		HTTPClient := isuphttp.HTTPClient{}
+		request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+
+		if test.clientPolicy {
+			HTTPClient.SetRetryPolicy(policy)
+		} else {
+			request = request.SetRetryPolicy(policy)
+		}
+
+		response := HTTPClient.HTTPCall(request)
+
+		server.Close()
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode)
+		assert.Equal(t, len(test.expectedAttempts), response.Attempts)
+
+		for index, attempt := range response.AttemptResults {
+			assert.Equal(t, test.expectedAttempts[index], attempt.StatusCode)
+		}
+	}
+}
+
+// Stop waiting for a retry when the context is cancelled
+func TestHTTPCallRetryCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := isuphttp.GetDefaultRetryPolicy()
+	policy.MaxBackoff = time.Minute
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	response := HTTPClient.HTTPCallContext(ctx, isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetRetryPolicy(policy))
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, isuphttp.StatusCanceled, response.StatusCode)
+	assert.Equal(t, 1, response.Attempts)
+	assert.Equal(t, 10*time.Second, response.AttemptResults[0].Wait)
+}
+
+// Stop retrying a call when the next retry would end after the max retry time
+func TestHTTPCallRetryMaxRetryTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/retry-after" {
+			w.Header().Set("Retry-After", "86400")
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := isuphttp.GetDefaultRetryPolicy()
+	policy.MaxAttempts = 1000
+	policy.BaseBackoff = 50 * time.Millisecond
+	policy.MaxBackoff = 50 * time.Millisecond
+	policy.MaxRetryTime = 300 * time.Millisecond
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	start := time.Now()
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetRetryPolicy(policy))
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Greater(t, response.Attempts, 1)
+	assert.Less(t, response.Attempts, 1000)
+
+	// A Retry-After longer than the max retry time isn't waited
+	policy.MaxBackoff = 0
+
+	start = time.Now()
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/retry-after").SetRetryPolicy(policy))
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, response.Attempts)
+	assert.Zero(t, response.AttemptResults[0].Wait)
+}
+
+// Send the body of a body reader again in every retry
+func TestHTTPCallRetryBodyReader(t *testing.T) {
+	var calls int32
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	policy := isuphttp.GetDefaultRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+
+	HTTPClient := isuphttp.HTTPClient{}
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, server.URL).
+		SetBodyReader(strings.NewReader("payload"), "text/plain").
+		SetRetryPolicy(policy))
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 2, response.Attempts)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+// Follow redirects as set by the request and record the followed ones
+func TestHTTPCallRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		request          isuphttp.HTTPRequest
+		expectedStatus   int
+		expectedFinalURL string
+		expectedChain    []isuphttp.RedirectHop
+	}{
+		{
+			isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/a"),
+			http.StatusOK,
+			server.URL + "/c",
+			[]isuphttp.RedirectHop{{server.URL + "/a", http.StatusFound, "/b"}, {server.URL + "/b", http.StatusMovedPermanently, "/c"}},
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/a").SetFollowRedirects(false),
+			http.StatusFound,
+			server.URL + "/a",
+			nil,
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.GET, server.URL+"/a").SetMaxRedirects(1),
+			isuphttp.StatusTooManyRedirects,
+			"",
+			[]isuphttp.RedirectHop{{server.URL + "/a", http.StatusFound, "/b"}},
+		},
+	}
+
+	for _, test := range tests {
+		HTTPClient := isuphttp.HTTPClient{}
+
+		response := HTTPClient.HTTPCall(test.request)
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode)
+		assert.Equal(t, test.expectedFinalURL, response.FinalURL)
+		assert.Equal(t, test.expectedChain, response.RedirectChain)
+	}
+}
+
+// Block redirects from https to http
+func TestHTTPCallInsecureRedirect(t *testing.T) {
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plainServer.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plainServer.URL, http.StatusFound)
+	}))
+	defer tlsServer.Close()
+
+	var tests = []struct {
+		block          bool
+		expectedStatus int
+		expectedHops   int
+	}{
+		{false, http.StatusOK, 1},
+		{true, isuphttp.StatusInsecureRedirect, 0},
+	}
+
+	for _, test := range tests {
+		HTTPClient := isuphttp.HTTPClient{}
+
+		request := isuphttp.GetHTTPRequest(isuphttp.GET, tlsServer.URL).SetInsecureRequest(true).SetBlockInsecureRedirects(test.block)
+
+		response := HTTPClient.HTTPCall(request)
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode)
+		assert.Len(t, response.RedirectChain, test.expectedHops)
+	}
+}
+
+// Limit and hash the response body
+func TestHTTPCallBodyLimits(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	hashOf := func(body string) string {
+		sum := sha256.Sum256([]byte(body))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var tests = []struct {
+		request           isuphttp.HTTPRequest
+		expectedBody      string
+		expectedSize      int64
+		expectedHash      string
+		expectedTruncated bool
+	}{
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL), content, 1000, hashOf(content), false},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetMaxBodySize(1000), content, 1000, hashOf(content), false},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetMaxBodySize(15), content[:15], 15, hashOf(content[:15]), true},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetHashBodyOnly(true), "", 1000, hashOf(content), false},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetHashBodyOnly(true).SetMaxBodySize(10), "", 10, hashOf(content[:10]), true},
+	}
+
+	for _, test := range tests {
+		HTTPClient := isuphttp.HTTPClient{}
+
+		response := HTTPClient.HTTPCall(test.request)
+
+		assert.Equal(t, test.expectedBody, response.Body)
+		assert.Equal(t, test.expectedSize, response.BodySize)
+		assert.Equal(t, test.expectedHash, response.BodySHA256)
+		assert.Equal(t, test.expectedTruncated, response.BodyTruncated)
+	}
+}
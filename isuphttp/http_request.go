@@ -1,16 +1,19 @@
 package isuphttp
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
 // HTTPRequest A request for a http call
 // If InsecureRequest is true the ssl certificate is not validated
 // TimeOut is the call timeout in milisecounds, default is 2000 ms, max is 60000 ms
+// Requests without a body send no body at all, a body set with SetBody is sent as JSON
+// SetFormBody, SetMultipartBody, SetRawBody and SetBodyReader choose other encodings
 type HTTPRequest struct {
 	url             string
 	method          string
@@ -20,6 +23,30 @@ type HTTPRequest struct {
 	cookies         map[string]interface{}
 	insecureRequest bool
 	timeOut         int
+	queryArrayStyle int
+	retryPolicy     *RetryPolicy
+	assertions      []Assertion
+	maxBodySize     int64
+	hashBodyOnly    bool
+	auth            AuthProvider
+	signer          Signer
+	tlsConfig       *TLSConfig
+
+	// Redirect policy
+	noRedirects            bool
+	maxRedirects           int
+	blockInsecureRedirects bool
+
+	// Body encoding and the values of non JSON bodies
+	bodyEncoding   int
+	multipartFiles []multipartFile
+	rawBody        []byte
+	bodyReader     io.Reader
+	rawContentType string
+
+	// If the values were set, so client defaults don't override them
+	insecureRequestSet bool
+	timeOutSet         bool
 }
 
 const (
@@ -28,6 +55,13 @@ const (
 	maxTimeout = 60000
 )
 
+// Styles to send slice query parameters
+const (
+	QueryArrayRepeat   = iota // ids=1&ids=2
+	QueryArrayBrackets        // ids[]=1&ids[]=2
+	QueryArrayComma           // ids=1,2
+)
+
 // GetHTTPRequest Instantiate a HTTP request object
 func GetHTTPRequest(method string, url string) HTTPRequest {
 	h := HTTPRequest{url: url, method: strings.ToUpper(method), timeOut: timeOut}
@@ -42,7 +76,7 @@ func (h HTTPRequest) SetHeaders(headers map[string]interface{}) HTTPRequest {
 	}
 
 	for index, value := range headers {
-		h.headers[index] = value
+		h.headers[http.CanonicalHeaderKey(index)] = value
 	}
 	return h
 }
@@ -53,12 +87,12 @@ func (h HTTPRequest) SetHeaderValue(name string, value interface{}) HTTPRequest
 		h.headers = make(map[string]interface{})
 	}
 
-	h.headers[name] = value
+	h.headers[http.CanonicalHeaderKey(name)] = value
 
 	return h
 }
 
-// SetBody Set body values
+// SetBody Set body values, sent as JSON
 func (h HTTPRequest) SetBody(body map[string]interface{}) HTTPRequest {
 	if h.body == nil {
 		h.body = make(map[string]interface{})
@@ -68,10 +102,13 @@ func (h HTTPRequest) SetBody(body map[string]interface{}) HTTPRequest {
 		h.body[index] = value
 	}
 
+	h.bodyEncoding = bodyJSON
+
 	return h
 }
 
 // SetQueryParams Set forms values
+// Slice values are sent as set by SetQueryArrayStyle
 func (h HTTPRequest) SetQueryParams(queryParams map[string]interface{}) HTTPRequest {
 	if h.queryParams == nil {
 		h.queryParams = make(map[string]interface{})
@@ -84,15 +121,60 @@ func (h HTTPRequest) SetQueryParams(queryParams map[string]interface{}) HTTPRequ
 	return h
 }
 
+// SetQueryArrayStyle Set how slice query parameters are sent, default is QueryArrayRepeat
+func (h HTTPRequest) SetQueryArrayStyle(style int) HTTPRequest {
+	h.queryArrayStyle = style
+	return h
+}
+
+// SetMaxBodySize Set the max number of response body bytes read, 0 means no limit
+// A longer body is truncated and the response BodyTruncated is true
+func (h HTTPRequest) SetMaxBodySize(size int64) HTTPRequest {
+	h.maxBodySize = size
+	return h
+}
+
+// SetHashBodyOnly Set if the response body is only hashed and counted, without being kept in the response
+func (h HTTPRequest) SetHashBodyOnly(hashOnly bool) HTTPRequest {
+	h.hashBodyOnly = hashOnly
+	return h
+}
+
+// SetCookies Set cookies values
+func (h HTTPRequest) SetCookies(cookies map[string]interface{}) HTTPRequest {
+	if h.cookies == nil {
+		h.cookies = make(map[string]interface{})
+	}
+
+	for index, value := range cookies {
+		h.cookies[index] = value
+	}
+
+	return h
+}
+
+// SetCookieValue Set a cookie value
+func (h HTTPRequest) SetCookieValue(name string, value interface{}) HTTPRequest {
+	if h.cookies == nil {
+		h.cookies = make(map[string]interface{})
+	}
+
+	h.cookies[name] = value
+
+	return h
+}
+
 // SetInsecureRequest Set if request is insecure (no cert validation)
 func (h HTTPRequest) SetInsecureRequest(InsecureRequest bool) HTTPRequest {
 	h.insecureRequest = InsecureRequest
+	h.insecureRequestSet = true
 	return h
 }
 
 // SetTimeOut Set request timeout
 func (h HTTPRequest) SetTimeOut(timeOut int) HTTPRequest {
 	h.timeOut = timeOut
+	h.timeOutSet = true
 
 	if h.timeOut < minTimeOut {
 		h.timeOut = minTimeOut
@@ -105,6 +187,21 @@ func (h HTTPRequest) SetTimeOut(timeOut int) HTTPRequest {
 	return h
 }
 
+// GetMethod Get the request method
+func (h HTTPRequest) GetMethod() string {
+	return h.method
+}
+
+// GetURL Get the request url, without the query parameters set by SetQueryParams
+func (h HTTPRequest) GetURL() string {
+	return h.url
+}
+
+// GetHeaderValue Get a header value, nil if the header isn't set
+func (h HTTPRequest) GetHeaderValue(name string) interface{} {
+	return h.headers[http.CanonicalHeaderKey(name)]
+}
+
 // GetTimeOut Get request timeout
 func (h HTTPRequest) GetTimeOut() int {
 	return h.timeOut
@@ -115,16 +212,103 @@ func (h HTTPRequest) GetInsecureRequest() bool {
 	return h.insecureRequest
 }
 
+// withDefaults Fill the request with the default values it doesn't set
+// A relative request url is joined to the default url, headers and query
+// parameters are merged and the request values win.
+func (h HTTPRequest) withDefaults(defaults HTTPRequest) HTTPRequest {
+	if h.method == "" {
+		h.method = defaults.method
+	}
+
+	h.url = joinURL(defaults.url, h.url)
+	h.headers = mergeValues(defaults.headers, h.headers)
+	h.queryParams = mergeValues(defaults.queryParams, h.queryParams)
+	h.cookies = mergeValues(defaults.cookies, h.cookies)
+
+	if !h.timeOutSet && defaults.timeOutSet {
+		h.timeOut = defaults.timeOut
+		h.timeOutSet = true
+	}
+
+	if h.retryPolicy == nil {
+		h.retryPolicy = defaults.retryPolicy
+	}
+
+	if h.auth == nil {
+		h.auth = defaults.auth
+	}
+
+	if h.signer == nil {
+		h.signer = defaults.signer
+	}
+
+	if h.tlsConfig == nil {
+		h.tlsConfig = defaults.tlsConfig
+	}
+
+	if !h.insecureRequestSet && defaults.insecureRequestSet {
+		h.insecureRequest = defaults.insecureRequest
+		h.insecureRequestSet = true
+	}
+
+	return h
+}
+
+// Join a relative url to a base url, absolute urls are kept
+func joinURL(base string, path string) string {
+	if base == "" || isAbsoluteURL(path) {
+		return path
+	}
+
+	if path == "" {
+		return base
+	}
+
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// Check if a url has a scheme and a host, a url in the query string doesn't count
+func isAbsoluteURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+
+	return err == nil && parsed.IsAbs() && parsed.Host != ""
+}
+
+// Merge two values maps in a new map, the values from override win
+// The map is always new, so changes to the merged request don't reach the caller request
+func mergeValues(defaults map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	if defaults == nil && override == nil {
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(defaults)+len(override))
+
+	for index, value := range defaults {
+		merged[index] = value
+	}
+
+	for index, value := range override {
+		merged[index] = value
+	}
+
+	return merged
+}
+
 // ToGoHTTPRequest Create a go http.Request from a HTTPRequest
 func (h HTTPRequest) ToGoHTTPRequest() (*http.Request, error) {
+	return h.ToGoHTTPRequestContext(context.Background())
+}
 
-	body, err := json.Marshal(h.body)
+// ToGoHTTPRequestContext Create a go http.Request with a context from a HTTPRequest
+func (h HTTPRequest) ToGoHTTPRequestContext(ctx context.Context) (*http.Request, error) {
+
+	body, contentType, err := h.encodeBody()
 
 	if err != nil {
 		return nil, err
 	}
 
-	request, errReq := http.NewRequest(h.method, h.getURLWithQueryParans(), bytes.NewBuffer(body))
+	request, errReq := http.NewRequestWithContext(ctx, h.method, h.getURLWithQueryParans(), body)
 
 	if errReq != nil {
 		return nil, errReq
@@ -134,34 +318,79 @@ func (h HTTPRequest) ToGoHTTPRequest() (*http.Request, error) {
 		request.Header.Set(index, fmt.Sprintf("%v", value))
 	}
 
+	// The multipart boundary is only known here, so its Content-Type always wins
+	if contentType != "" && (request.Header.Get("Content-Type") == "" || h.bodyEncoding == bodyMultipart) {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	// Sorted, so the Cookie header is the same on every call
+	for _, name := range sortedKeys(h.cookies) {
+		request.AddCookie(&http.Cookie{Name: name, Value: fmt.Sprintf("%v", h.cookies[name])})
+	}
+
 	return request, nil
 }
 
 // Return the url with query parameters
-func (h HTTPRequest) getURLWithQueryParans() (url string) {
-	url = h.url
-
+// The query already in the url is kept as it is, the parameters replace its pairs
+// with the same name and are added escaped and sorted by name, so the same request
+// always gives the same url.
+func (h HTTPRequest) getURLWithQueryParans() string {
 	if len(h.queryParams) == 0 {
-		return
+		return h.url
 	}
 
-	if !strings.Contains(url, "?") {
-		url += "?"
-	}
+	base, fragment, hasFragment := strings.Cut(h.url, "#")
+	base, rawQuery, _ := strings.Cut(base, "?")
+
+	params := url.Values{}
+	replaced := map[string]bool{}
+
+	for name, value := range h.queryParams {
+		values := valueStrings(value)
 
-	for index, val := range h.queryParams {
-		if v, ok := val.(bool); ok {
-			if v {
-				url += fmt.Sprintf("&%s=true", index)
-			} else {
-				url += fmt.Sprintf("&%s=false", index)
+		if isListValue(value) {
+			switch h.queryArrayStyle {
+			case QueryArrayBrackets:
+				replaced[name] = true
+				name += "[]"
+			case QueryArrayComma:
+				values = []string{strings.Join(values, ",")}
 			}
-		} else {
-			url += fmt.Sprintf("&%s=%v", index, val)
 		}
+
+		params[name] = values
+		replaced[name] = true
+	}
+
+	// The url query is kept as it is, without the pairs replaced by the query parameters
+	pairs := []string{}
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+
+		if name, err := url.QueryUnescape(key); pair == "" || (err == nil && replaced[name]) {
+			continue
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	if encoded := params.Encode(); encoded != "" {
+		pairs = append(pairs, encoded)
+	}
+
+	fullURL := base
+
+	if len(pairs) > 0 {
+		fullURL += "?" + strings.Join(pairs, "&")
+	}
+
+	if hasFragment {
+		fullURL += "#" + fragment
 	}
 
-	return
+	return fullURL
 }
 
 // SetContentType Set the request Content-Type header
@@ -44,3 +44,107 @@ func TestGetHTTPRequestQueryParameters(t *testing.T) {
 
 	}
 }
+
+func TestHTTPRequestWithDefaults(t *testing.T) {
+	defaults := GetHTTPRequest("", "https://api.example.com/v1/").
+		SetAuthorization("Bearer default").
+		SetAccept(ApplicationJSON).
+		SetQueryParams(map[string]interface{}{"lang": "pt", "page": 1}).
+		SetTimeOut(5000).
+		SetInsecureRequest(true)
+
+	var tests = []struct {
+		request         HTTPRequest
+		expectedURL     string
+		expectedHeaders map[string]interface{}
+		expectedQuery   map[string]interface{}
+		expectedTimeOut int
+		expectedInsec   bool
+	}{
+		{
+			GetHTTPRequest(GET, "/users"),
+			"https://api.example.com/v1/users",
+			map[string]interface{}{"Authorization": "Bearer default", "Accept": ApplicationJSON},
+			map[string]interface{}{"lang": "pt", "page": 1},
+			5000,
+			true,
+		},
+		{
+			GetHTTPRequest(GET, "https://other.example.com/status").SetHeaderValue("authorization", "Bearer mine").SetTimeOut(100),
+			"https://other.example.com/status",
+			map[string]interface{}{"Authorization": "Bearer mine", "Accept": ApplicationJSON},
+			map[string]interface{}{"lang": "pt", "page": 1},
+			100,
+			true,
+		},
+		{
+			GetHTTPRequest(GET, "/go?next=https://x.example/"),
+			"https://api.example.com/v1/go?next=https://x.example/",
+			map[string]interface{}{"Authorization": "Bearer default", "Accept": ApplicationJSON},
+			map[string]interface{}{"lang": "pt", "page": 1},
+			5000,
+			true,
+		},
+		{
+			GetHTTPRequest(GET, "").SetQueryParams(map[string]interface{}{"page": 2}).SetInsecureRequest(false),
+			"https://api.example.com/v1/",
+			map[string]interface{}{"Authorization": "Bearer default", "Accept": ApplicationJSON},
+			map[string]interface{}{"lang": "pt", "page": 2},
+			5000,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		request := test.request.withDefaults(defaults)
+
+		assert.Equal(t, test.expectedURL, request.url)
+		assert.Equal(t, test.expectedHeaders, request.headers)
+		assert.Equal(t, test.expectedQuery, request.queryParams)
+		assert.Equal(t, test.expectedTimeOut, request.GetTimeOut())
+		assert.Equal(t, test.expectedInsec, request.GetInsecureRequest())
+	}
+}
+
+func TestHTTPRequestWithEmptyDefaults(t *testing.T) {
+	request := GetHTTPRequest(GET, "localhost:8080/api").SetHeaderValue("App", "123")
+
+	assert.Equal(t, request, request.withDefaults(HTTPRequest{}))
+}
+
+func TestGetHTTPRequestURLWithQueryParameters(t *testing.T) {
+	var tests = []struct {
+		apiURL          string
+		queryParameters map[string]interface{}
+		arrayStyle      int
+		expectedURL     string
+	}{
+		{"localhost:8080/api", nil, QueryArrayRepeat, "localhost:8080/api"},
+		{"localhost:8080/api", map[string]interface{}{"b": 2, "a": 1}, QueryArrayRepeat, "localhost:8080/api?a=1&b=2"},
+		{"localhost:8080/api", map[string]interface{}{"q": "a b&c=d", "é": "ç"}, QueryArrayRepeat, "localhost:8080/api?q=a+b%26c%3Dd&%C3%A9=%C3%A7"},
+		{"localhost:8080/api", map[string]interface{}{"ids": []int{1, 2}}, QueryArrayRepeat, "localhost:8080/api?ids=1&ids=2"},
+		{"localhost:8080/api", map[string]interface{}{"ids": []string{"1", "2"}}, QueryArrayBrackets, "localhost:8080/api?ids%5B%5D=1&ids%5B%5D=2"},
+		{"localhost:8080/api", map[string]interface{}{"ids": []interface{}{1, "b"}}, QueryArrayComma, "localhost:8080/api?ids=1%2Cb"},
+		{"localhost:8080/api?z=9&a=0", map[string]interface{}{"a": 1, "b": true}, QueryArrayRepeat, "localhost:8080/api?z=9&a=1&b=true"},
+		{"localhost:8080/api?b=%2f&a=1;c=2&ids[]=0", map[string]interface{}{"ids": []int{1}, "d": 4}, QueryArrayBrackets, "localhost:8080/api?b=%2f&a=1;c=2&d=4&ids%5B%5D=1"},
+		{"localhost:8080/api?ids=0&x", map[string]interface{}{"ids": []int{1, 2}}, QueryArrayComma, "localhost:8080/api?x&ids=1%2C2"},
+		{"https://localhost/api?x=1#section", map[string]interface{}{"y": 2}, QueryArrayRepeat, "https://localhost/api?x=1&y=2#section"},
+		{"https://localhost/api?", map[string]interface{}{"y": 2}, QueryArrayRepeat, "https://localhost/api?y=2"},
+		{"localhost:8080/api", map[string]interface{}{"ids": []int{}}, QueryArrayRepeat, "localhost:8080/api"},
+		{"localhost:8080/api#section", map[string]interface{}{"ids": []int{}}, QueryArrayBrackets, "localhost:8080/api#section"},
+		{"localhost:8080/api?ids=0", map[string]interface{}{"ids": []int{}}, QueryArrayRepeat, "localhost:8080/api"},
+		{"localhost:8080/api?x=1", map[string]interface{}{"ids": []int{}}, QueryArrayRepeat, "localhost:8080/api?x=1"},
+	}
+
+	for _, test := range tests {
+		request := GetHTTPRequest(GET, test.apiURL).SetQueryArrayStyle(test.arrayStyle)
+
+		if test.queryParameters != nil {
+			request = request.SetQueryParams(test.queryParameters)
+		}
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, test.expectedURL, request.getURLWithQueryParans())
+		}
+	}
+}
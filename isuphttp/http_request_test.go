@@ -1,6 +1,10 @@
 package isuphttp_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"strings"
 	"testing"
 
 	"github.com/psenna/isup-http-client/isuphttp"
@@ -193,3 +197,139 @@ func TestHTTPRequestSetHeaderAuthorization(t *testing.T) {
 
 	}
 }
+
+func TestHTTPRequestSetCookies(t *testing.T) {
+	var tests = []struct {
+		baseCookies    map[string]interface{}
+		cookiesToSet   map[string]interface{}
+		expectedHeader string
+	}{
+		{map[string]interface{}{}, map[string]interface{}{}, ""},
+		{map[string]interface{}{"session": "abc"}, map[string]interface{}{}, "session=abc"},
+		{map[string]interface{}{"session": "abc", "id": 12}, map[string]interface{}{"lang": "pt"}, "id=12; lang=pt; session=abc"},
+		{map[string]interface{}{"session": "abc"}, map[string]interface{}{"session": "def"}, "session=def"},
+	}
+
+	for _, test := range tests {
+		request := isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/api")
+
+		if len(test.baseCookies) > 0 {
+			request = request.SetCookies(test.baseCookies)
+		}
+
+		for name, value := range test.cookiesToSet {
+			request = request.SetCookieValue(name, value)
+		}
+
+		requestGo, _ := request.ToGoHTTPRequest()
+
+		assert.Equal(t, test.expectedHeader, requestGo.Header.Get("Cookie"))
+	}
+}
+
+func TestHTTPRequestBodyEncoding(t *testing.T) {
+	var tests = []struct {
+		request             isuphttp.HTTPRequest
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").SetBody(map[string]interface{}{"name": "isup"}),
+			isuphttp.ApplicationJSON,
+			`{"name":"isup"}`,
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").SetFormBody(map[string]interface{}{"user": "isup", "pass": "a&b", "ids": []int{1, 2}}),
+			isuphttp.FormURLEncoded,
+			"ids=1&ids=2&pass=a%26b&user=isup",
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").SetRawBody([]byte("<ping/>"), isuphttp.ApplicationXML),
+			isuphttp.ApplicationXML,
+			"<ping/>",
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").SetBodyReader(strings.NewReader("raw bytes"), ""),
+			"",
+			"raw bytes",
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").SetContentType("text/xml").SetRawBody([]byte("<soap/>"), isuphttp.ApplicationXML),
+			"text/xml",
+			"<soap/>",
+		},
+	}
+
+	for _, test := range tests {
+		requestGo, err := test.request.ToGoHTTPRequest()
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedContentType, requestGo.Header.Get("Content-Type"))
+
+		body, _ := ioutil.ReadAll(requestGo.Body)
+
+		assert.Equal(t, test.expectedBody, string(body))
+	}
+}
+
+func TestHTTPRequestMultipartBody(t *testing.T) {
+	request := isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/api").
+		SetContentType(isuphttp.ApplicationJSON).
+		SetMultipartBody(map[string]interface{}{"description": "report"}).
+		AddMultipartFile("file", "report.csv", []byte("a,b\n1,2\n"))
+
+	requestGo, err := request.ToGoHTTPRequest()
+	assert.Nil(t, err)
+
+	mediaType, _, _ := mime.ParseMediaType(requestGo.Header.Get("Content-Type"))
+	assert.Equal(t, isuphttp.MultipartForm, mediaType)
+
+	assert.Nil(t, requestGo.ParseMultipartForm(1024))
+	assert.Equal(t, "report", requestGo.FormValue("description"))
+
+	file, header, err := requestGo.FormFile("file")
+	if assert.Nil(t, err) {
+		content, _ := ioutil.ReadAll(file)
+
+		assert.Equal(t, "report.csv", header.Filename)
+		assert.Equal(t, "a,b\n1,2\n", string(content))
+	}
+}
+
+func TestHTTPRequestWithoutBody(t *testing.T) {
+	var tests = []struct {
+		apiMethod string
+	}{
+		{isuphttp.GET}, {isuphttp.HEAD}, {isuphttp.DELETE}, {isuphttp.POST},
+	}
+
+	for _, test := range tests {
+		requestGo, err := isuphttp.GetHTTPRequest(test.apiMethod, "localhost:8080/api").ToGoHTTPRequest()
+
+		assert.Nil(t, err)
+		assert.Nil(t, requestGo.Body)
+		assert.Equal(t, int64(0), requestGo.ContentLength)
+		assert.Equal(t, "", requestGo.Header.Get("Content-Type"))
+	}
+}
+
+func TestHTTPRequestBodyNotAllowed(t *testing.T) {
+	var tests = []struct {
+		apiMethod     string
+		expectedError bool
+	}{
+		{isuphttp.HEAD, true},
+		{isuphttp.TRACE, true},
+		{isuphttp.CONNECT, true},
+		{isuphttp.GET, false},
+		{isuphttp.DELETE, false},
+	}
+
+	for _, test := range tests {
+		request := isuphttp.GetHTTPRequest(test.apiMethod, "localhost:8080/api").SetBody(map[string]interface{}{"a": 1})
+
+		_, err := request.ToGoHTTPRequest()
+
+		assert.Equal(t, test.expectedError, errors.Is(err, isuphttp.ErrBodyNotAllowed), test.apiMethod)
+	}
+}
@@ -1,11 +1,28 @@
 package isuphttp
 
 import (
-	"io/ioutil"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"mime"
 	"net/http"
+	"time"
 )
 
 // HTTPResponse A response from a http call
+// Headers has every response header with its canonical name and a []string with all of its values
+// Cookies has the cookies set by the response Set-Cookie headers
+// ContentType is the media type of the Content-Type header, its parameters (charset, boundary) are in ContentTypeParams
+// ResponseTime is the time in miliseconds until the response headers arrive
+// DNSLookup, Connect and TLSHandshake are zero when a pooled connection is reused
+// TimeToFirstByte is the time from the request being sent to the first response byte (server processing)
+// ContentTransfer is the time from the first response byte to the end of the body
+// FinalURL is the full url of the last request and RedirectChain has every redirect followed before it
+// Attempts and AttemptResults have the number and outcome of each attempt, when a retry policy is set
+// BodySize and BodySHA256 are the size and hash of the body bytes read, BodyTruncated is true if the body was longer than the max body size
+// Verdict and FailedAssertions are the result of the request assertions, Verdict is empty without assertions
 type HTTPResponse struct {
 	URL           string
 	Method        string
@@ -16,16 +33,44 @@ type HTTPResponse struct {
 	ContentType   string
 	Error         string
 	Headers       map[string]interface{}
+
+	ContentTypeParams map[string]string
+	Cookies           []*http.Cookie
+
+	Attempts       int
+	AttemptResults []HTTPAttempt
+
+	FinalURL      string
+	RedirectChain []RedirectHop
+
+	Verdict          string
+	FailedAssertions []AssertionFailure
+
+	BodySize      int64
+	BodySHA256    string
+	BodyTruncated bool
+
+	DNSLookup       time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration
+	ContentTransfer time.Duration
 }
 
 // GetHTTPResponse Instantiate a HTTP request object
 func GetHTTPResponse(response *http.Response) HTTPResponse {
+	return readHTTPResponse(response, 0, false)
+}
+
+// readHTTPResponse Instantiate a HTTP response object, reading at most maxBodySize bytes of the body
+// maxBodySize 0 means no limit, if hashOnly is true the body is hashed and counted but not kept
+func readHTTPResponse(response *http.Response, maxBodySize int64, hashOnly bool) HTTPResponse {
 
-	bodyBytes, err := ioutil.ReadAll(response.Body)
+	body, err := readBody(response.Body, maxBodySize, hashOnly)
 	bodyString := ""
 
 	if err == nil {
-		bodyString = string(bodyBytes)
+		bodyString = body.content.String()
 	}
 
 	h := HTTPResponse{
@@ -34,7 +79,102 @@ func GetHTTPResponse(response *http.Response) HTTPResponse {
 		StatusCode:    response.StatusCode,
 		Body:          bodyString,
 		ContentLength: response.ContentLength,
+		Headers:       make(map[string]interface{}, len(response.Header)),
+		Cookies:       response.Cookies(),
+		FinalURL:      response.Request.URL.String(),
+		BodySize:      body.size,
+		BodySHA256:    hex.EncodeToString(body.hash.Sum(nil)),
+		BodyTruncated: body.truncated,
 	}
 
+	for name, values := range response.Header {
+		h.Headers[name] = append([]string(nil), values...)
+	}
+
+	h.ContentType, h.ContentTypeParams = parseContentType(response.Header.Get("Content-Type"))
+
 	return h
 }
+
+// responseBody The body read from a response
+type responseBody struct {
+	content   bytes.Buffer
+	hash      hash.Hash
+	size      int64
+	truncated bool
+}
+
+// Read a response body through a SHA-256 hasher, keeping its content unless hashOnly is true
+func readBody(reader io.Reader, maxBodySize int64, hashOnly bool) (*responseBody, error) {
+	body := &responseBody{hash: sha256.New()}
+
+	var writer io.Writer = body.hash
+	if !hashOnly {
+		writer = io.MultiWriter(body.hash, &body.content)
+	}
+
+	limited := reader
+	if maxBodySize > 0 {
+		limited = io.LimitReader(reader, maxBodySize)
+	}
+
+	size, err := io.Copy(writer, limited)
+	body.size = size
+
+	if err != nil {
+		return body, err
+	}
+
+	// One more byte tells if the body was longer than the limit
+	if maxBodySize > 0 && size == maxBodySize {
+		extra, _ := io.ReadFull(reader, make([]byte, 1))
+		body.truncated = extra > 0
+	}
+
+	return body, nil
+}
+
+// GetHeader Get the first value of a response header
+func (r HTTPResponse) GetHeader(name string) string {
+	values := r.GetHeaderValues(name)
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// GetHeaderValues Get all the values of a response header
+func (r HTTPResponse) GetHeaderValues(name string) []string {
+	value, ok := r.Headers[http.CanonicalHeaderKey(name)]
+
+	if !ok {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	}
+
+	return nil
+}
+
+// Split a Content-Type header in media type and parameters
+// If the header can't be parsed the raw value is returned as media type
+func parseContentType(contentType string) (string, map[string]string) {
+	if contentType == "" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return contentType, nil
+	}
+
+	return mediaType, params
+}
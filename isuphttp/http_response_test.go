@@ -0,0 +1,66 @@
+package isuphttp_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHTTPResponseHeaders(t *testing.T) {
+	var tests = []struct {
+		headers           http.Header
+		expectedHeaders   map[string][]string
+		contentType       string
+		contentTypeParams map[string]string
+	}{
+		{http.Header{}, map[string][]string{}, "", nil},
+		{
+			http.Header{"Server": {"nginx"}, "Cache-Control": {"no-cache"}},
+			map[string][]string{"Server": {"nginx"}, "Cache-Control": {"no-cache"}},
+			"",
+			nil,
+		},
+		{
+			http.Header{"Set-Cookie": {"a=1", "b=2"}, "Content-Type": {"application/json; charset=utf-8"}},
+			map[string][]string{"Set-Cookie": {"a=1", "b=2"}, "Content-Type": {"application/json; charset=utf-8"}},
+			isuphttp.ApplicationJSON,
+			map[string]string{"charset": "utf-8"},
+		},
+		{
+			http.Header{"Content-Type": {"multipart/form-data; boundary=xyz"}},
+			map[string][]string{"Content-Type": {"multipart/form-data; boundary=xyz"}},
+			"multipart/form-data",
+			map[string]string{"boundary": "xyz"},
+		},
+		{
+			http.Header{"Content-Type": {"not a media type;;"}},
+			map[string][]string{"Content-Type": {"not a media type;;"}},
+			"not a media type;;",
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		response := isuphttp.GetHTTPResponse(&http.Response{
+			StatusCode: http.StatusOK,
+			Header:     test.headers,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    &http.Request{Method: isuphttp.GET, URL: &url.URL{Host: "localhost:8080"}},
+		})
+
+		assert.Equal(t, len(test.expectedHeaders), len(response.Headers))
+
+		for name, values := range test.expectedHeaders {
+			assert.Equal(t, values, response.GetHeaderValues(name))
+			assert.Equal(t, values[0], response.GetHeader(strings.ToLower(name)))
+		}
+
+		assert.Equal(t, test.contentType, response.ContentType)
+		assert.Equal(t, test.contentTypeParams, response.ContentTypeParams)
+	}
+}
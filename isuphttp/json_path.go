@@ -0,0 +1,352 @@
+package isuphttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrJSONPathNotFound Error for a JSON path that isn't in the body
+var ErrJSONPathNotFound = errors.New("json path not found")
+
+// JSONValue Get a value of a JSON body
+// Paths starting with $ are JSONPath, like $.db.healthy or $.items[0]['name'],
+// other paths are RFC 6901 JSON Pointer, like /db/healthy or /items/0/name
+func (r HTTPResponse) JSONValue(path string) (interface{}, error) {
+	var document interface{}
+
+	if err := json.Unmarshal([]byte(r.Body), &document); err != nil {
+		return nil, fmt.Errorf("invalid json body: %w", err)
+	}
+
+	var tokens []interface{}
+	var err error
+
+	if strings.HasPrefix(path, "$") {
+		tokens, err = parseJSONPath(path)
+	} else {
+		tokens, err = parseJSONPointer(path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupJSON(document, tokens, path)
+}
+
+// JSONString Get a string value of a JSON body
+func (r HTTPResponse) JSONString(path string) (string, error) {
+	value, err := r.JSONValue(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	return "", fmt.Errorf("json path %s is not a string", path)
+}
+
+// JSONNumber Get a number value of a JSON body
+func (r HTTPResponse) JSONNumber(path string) (float64, error) {
+	value, err := r.JSONValue(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n, ok := value.(float64); ok {
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("json path %s is not a number", path)
+}
+
+// JSONBool Get a boolean value of a JSON body
+func (r HTTPResponse) JSONBool(path string) (bool, error) {
+	value, err := r.JSONValue(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+
+	return false, fmt.Errorf("json path %s is not a boolean", path)
+}
+
+// JSONLength Get the length of an array, object or string of a JSON body
+func (r HTTPResponse) JSONLength(path string) (int, error) {
+	value, err := r.JSONValue(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	case string:
+		return len(v), nil
+	}
+
+	return 0, fmt.Errorf("json path %s has no length", path)
+}
+
+// ExpectJSONExists The JSON body has path
+func ExpectJSONExists(path string) Assertion {
+	return assertion{
+		description: fmt.Sprintf("json %s exists", path),
+		check: func(response HTTPResponse) error {
+			_, err := response.JSONValue(path)
+			return err
+		},
+	}
+}
+
+// ExpectJSONEquals The JSON body value at path is value
+func ExpectJSONEquals(path string, value interface{}) Assertion {
+	return assertion{
+		description: fmt.Sprintf("json %s is %v", path, value),
+		check: func(response HTTPResponse) error {
+			actual, err := response.JSONValue(path)
+
+			if err != nil {
+				return err
+			}
+
+			expected, err := normalizeJSON(value)
+
+			if err != nil {
+				return err
+			}
+
+			if !reflect.DeepEqual(expected, actual) {
+				return fmt.Errorf("json %s is %v", path, actual)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectJSONGreaterThan The JSON body number at path is greater than value
+func ExpectJSONGreaterThan(path string, value float64) Assertion {
+	return assertion{
+		description: fmt.Sprintf("json %s > %v", path, value),
+		check: func(response HTTPResponse) error {
+			actual, err := response.JSONNumber(path)
+
+			if err != nil {
+				return err
+			}
+
+			if actual <= value {
+				return fmt.Errorf("json %s is %v", path, actual)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectJSONLessThan The JSON body number at path is less than value
+func ExpectJSONLessThan(path string, value float64) Assertion {
+	return assertion{
+		description: fmt.Sprintf("json %s < %v", path, value),
+		check: func(response HTTPResponse) error {
+			actual, err := response.JSONNumber(path)
+
+			if err != nil {
+				return err
+			}
+
+			if actual >= value {
+				return fmt.Errorf("json %s is %v", path, actual)
+			}
+
+			return nil
+		},
+	}
+}
+
+// ExpectJSONLength The JSON body array, object or string at path has length items
+func ExpectJSONLength(path string, length int) Assertion {
+	return assertion{
+		description: fmt.Sprintf("json %s has length %d", path, length),
+		check: func(response HTTPResponse) error {
+			actual, err := response.JSONLength(path)
+
+			if err != nil {
+				return err
+			}
+
+			if actual != length {
+				return fmt.Errorf("json %s has length %d", path, actual)
+			}
+
+			return nil
+		},
+	}
+}
+
+// Get the value of a JSON document at the path tokens
+// String tokens are object keys and int tokens are array indexes
+func lookupJSON(document interface{}, tokens []interface{}, path string) (interface{}, error) {
+	current := document
+
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			key, ok := token.(string)
+			if !ok {
+				key = strconv.Itoa(token.(int))
+			}
+
+			value, found := node[key]
+			if !found {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+
+			current = value
+		case []interface{}:
+			index, ok := token.(int)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+
+			if index < 0 {
+				index += len(node)
+			}
+
+			if index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+	}
+
+	return current, nil
+}
+
+// Parse a JSONPath with dot and bracket children, like $.items[0]['name']
+// Wildcards, filters and recursive descent are not supported
+func parseJSONPath(path string) ([]interface{}, error) {
+	var tokens []interface{}
+
+	rest := strings.TrimPrefix(path, "$")
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("invalid json path %s: recursive descent is not supported", path)
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+
+			name := rest[1 : end+1]
+			if name == "" || name == "*" {
+				return nil, fmt.Errorf("invalid json path %s: invalid name %q", path, name)
+			}
+
+			tokens = append(tokens, name)
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid json path %s: missing ]", path)
+			}
+
+			selector := rest[1:end]
+
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				tokens = append(tokens, selector[1:len(selector)-1])
+			} else if index, err := strconv.Atoi(selector); err == nil {
+				tokens = append(tokens, index)
+			} else {
+				return nil, fmt.Errorf("invalid json path %s: invalid selector [%s]", path, selector)
+			}
+
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid json path %s", path)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parse a RFC 6901 JSON Pointer, like /items/0/name
+// Numeric tokens are array indexes or object keys, as the document requires
+func parseJSONPointer(pointer string) ([]interface{}, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid json pointer %s: must start with /", pointer)
+	}
+
+	parts := strings.Split(pointer[1:], "/")
+	tokens := make([]interface{}, len(parts))
+
+	for i, part := range parts {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+
+		if isJSONPointerIndex(part) {
+			if index, err := strconv.Atoi(part); err == nil {
+				tokens[i] = index
+				continue
+			}
+		}
+
+		tokens[i] = part
+	}
+
+	return tokens, nil
+}
+
+// If a JSON Pointer token is an array index, digits without leading zeros
+func isJSONPointerIndex(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Convert a go value to the types given by json.Unmarshal, so it can be compared
+func normalizeJSON(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+
+	err = json.Unmarshal(encoded, &normalized)
+
+	return normalized, err
+}
@@ -0,0 +1,118 @@
+package isuphttp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+const healthBody = `{"status":"ok","db":{"healthy":true,"latency":12.5},"queue":{"depth":250},"items":[{"name":"a"},{"name":"b"}],"a/b":{"m~n":1},"10":"ten"}`
+
+func TestHTTPResponseJSONValue(t *testing.T) {
+	response := isuphttp.HTTPResponse{Body: healthBody}
+
+	var tests = []struct {
+		path          string
+		expectedValue interface{}
+		expectedError bool
+	}{
+		{"$.status", "ok", false},
+		{"$.db.healthy", true, false},
+		{"$['db']['latency']", 12.5, false},
+		{"$.items[1].name", "b", false},
+		{"$.items[-1]['name']", "b", false},
+		{"$.items[2].name", nil, true},
+		{"$.missing", nil, true},
+		{"$..name", nil, true},
+		{"$.items[*]", nil, true},
+		{"$.status.name", nil, true},
+		{"/status", "ok", false},
+		{"/db/healthy", true, false},
+		{"/items/0/name", "a", false},
+		{"/items/01/name", nil, true},
+		{"/a~1b/m~0n", float64(1), false},
+		{"/10", "ten", false},
+		{"status", nil, true},
+	}
+
+	for _, test := range tests {
+		value, err := response.JSONValue(test.path)
+
+		assert.Equal(t, test.expectedError, err != nil, test.path)
+		assert.Equal(t, test.expectedValue, value, test.path)
+	}
+
+	document, err := response.JSONValue("")
+	assert.Nil(t, err)
+	assert.IsType(t, map[string]interface{}{}, document)
+
+	_, err = response.JSONValue("$.missing")
+	assert.True(t, errors.Is(err, isuphttp.ErrJSONPathNotFound))
+
+	_, err = isuphttp.HTTPResponse{Body: "not json"}.JSONValue("$.status")
+	assert.NotNil(t, err)
+}
+
+func TestHTTPResponseJSONTypedValues(t *testing.T) {
+	response := isuphttp.HTTPResponse{Body: healthBody}
+
+	status, err := response.JSONString("$.status")
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", status)
+
+	depth, err := response.JSONNumber("/queue/depth")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(250), depth)
+
+	healthy, err := response.JSONBool("$.db.healthy")
+	assert.Nil(t, err)
+	assert.True(t, healthy)
+
+	length, err := response.JSONLength("$.items")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, length)
+
+	_, err = response.JSONNumber("$.status")
+	assert.NotNil(t, err)
+
+	_, err = response.JSONBool("$.status")
+	assert.NotNil(t, err)
+
+	_, err = response.JSONString("$.db")
+	assert.NotNil(t, err)
+
+	_, err = response.JSONLength("$.db.healthy")
+	assert.NotNil(t, err)
+}
+
+func TestJSONAssertions(t *testing.T) {
+	response := isuphttp.HTTPResponse{StatusCode: 200, Body: healthBody}
+
+	var tests = []struct {
+		assertion  isuphttp.Assertion
+		expectedUp bool
+	}{
+		{isuphttp.ExpectJSONEquals("$.db.healthy", true), true},
+		{isuphttp.ExpectJSONEquals("$.db.healthy", false), false},
+		{isuphttp.ExpectJSONEquals("$.queue.depth", 250), true},
+		{isuphttp.ExpectJSONEquals("$.items[0]", map[string]string{"name": "a"}), true},
+		{isuphttp.ExpectJSONEquals("$.missing", nil), false},
+		{isuphttp.ExpectJSONExists("/db/latency"), true},
+		{isuphttp.ExpectJSONExists("/db/version"), false},
+		{isuphttp.ExpectJSONLessThan("$.queue.depth", 1000), true},
+		{isuphttp.ExpectJSONLessThan("$.queue.depth", 100), false},
+		{isuphttp.ExpectJSONGreaterThan("$.db.latency", 10), true},
+		{isuphttp.ExpectJSONGreaterThan("$.db.latency", 12.5), false},
+		{isuphttp.ExpectJSONGreaterThan("$.status", 1), false},
+		{isuphttp.ExpectJSONLength("$.items", 2), true},
+		{isuphttp.ExpectJSONLength("$.items", 3), false},
+	}
+
+	for _, test := range tests {
+		verdict, _ := isuphttp.EvaluateAssertions(response, []isuphttp.Assertion{test.assertion})
+
+		assert.Equal(t, test.expectedUp, verdict == isuphttp.VerdictUp, test.assertion.String())
+	}
+}
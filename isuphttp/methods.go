@@ -11,4 +11,5 @@ const (
 	DELETE  = "DELETE"
 	CONNECT = "CONNECT"
 	OPTIONS = "OPTIONS"
+	TRACE   = "TRACE"
 )
@@ -0,0 +1,95 @@
+package isuphttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+)
+
+// Default header of the request id
+const requestIDHeader = "X-Request-Id"
+
+// Handler Make a call and get its response
+type Handler func(ctx context.Context, request HTTPRequest) HTTPResponse
+
+// Middleware A step around every call of a client
+// It can change the request before calling next, answer the call without calling next,
+// or change the response returned by next
+type Middleware func(ctx context.Context, request HTTPRequest, next Handler) HTTPResponse
+
+// Use Add middlewares to the client, they run in the order they are added
+// Middlewares get the request with the client defaults and wrap the retries and
+// assertions, so their response has every attempt and the verdict
+func (c *HTTPClient) Use(middlewares ...Middleware) {
+	c.middlewares = append(append([]Middleware(nil), c.middlewares...), middlewares...)
+}
+
+// chain Get the handler that runs the client middlewares before the call
+func (c HTTPClient) chain(call Handler) Handler {
+	handler := call
+
+	for index := len(c.middlewares) - 1; index >= 0; index-- {
+		middleware, next := c.middlewares[index], handler
+
+		handler = func(ctx context.Context, request HTTPRequest) HTTPResponse {
+			return middleware(ctx, request, next)
+		}
+	}
+
+	return handler
+}
+
+// RequestIDMiddleware Set a random id in a request header, X-Request-Id if header is empty
+// Requests that already have the header keep their id
+func RequestIDMiddleware(header string) Middleware {
+	if header == "" {
+		header = requestIDHeader
+	}
+
+	return func(ctx context.Context, request HTTPRequest, next Handler) HTTPResponse {
+		if request.GetHeaderValue(header) == nil {
+			request = request.SetHeaderValue(header, newRequestID())
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// UserAgentMiddleware Set the User-Agent header of the requests without one
+func UserAgentMiddleware(userAgent string) Middleware {
+	return func(ctx context.Context, request HTTPRequest, next Handler) HTTPResponse {
+		if request.GetHeaderValue("User-Agent") == nil {
+			request = request.SetHeaderValue("User-Agent", userAgent)
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// LoggingMiddleware Log every call with its status code, response time and error, log.Default() if logger is nil
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return func(ctx context.Context, request HTTPRequest, next Handler) HTTPResponse {
+		response := next(ctx, request)
+
+		if response.Error != "" {
+			logger.Printf("%s %s %d %.0fms error: %s", request.method, request.url, response.StatusCode, response.ResponseTime, response.Error)
+		} else {
+			logger.Printf("%s %s %d %.0fms", request.method, request.url, response.StatusCode, response.ResponseTime)
+		}
+
+		return response
+	}
+}
+
+// newRequestID Get a random request id
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
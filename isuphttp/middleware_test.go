@@ -0,0 +1,144 @@
+package isuphttp_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+	transport.AddMockResponse(isuphttp.HTTPResponse{StatusCode: http.StatusOK, Body: "ok"}, isuphttp.GET, "https://api.local/users")
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.SetDefaultRequest(isuphttp.GetHTTPRequest("", "https://api.local"))
+
+	order := []string{}
+
+	trace := func(name string) isuphttp.Middleware {
+		return func(ctx context.Context, request isuphttp.HTTPRequest, next isuphttp.Handler) isuphttp.HTTPResponse {
+			order = append(order, name+" before")
+			response := next(ctx, request.SetHeaderValue("X-Trace", name))
+			order = append(order, name+" after")
+
+			return response
+		}
+	}
+
+	HTTPClient.Use(trace("first"), trace("second"))
+	HTTPClient.Use(func(ctx context.Context, request isuphttp.HTTPRequest, next isuphttp.Handler) isuphttp.HTTPResponse {
+		// The request has the client defaults
+		assert.Equal(t, "https://api.local/users", request.GetURL())
+		assert.Equal(t, isuphttp.GET, request.GetMethod())
+		assert.Equal(t, "second", request.GetHeaderValue("x-trace"))
+
+		response := next(ctx, request)
+		response.Body = "changed " + response.Body
+
+		return response
+	})
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "/users").
+		SetAssertions(isuphttp.ExpectBodyContains("ok")))
+
+	assert.Equal(t, []string{"first before", "second before", "second after", "first after"}, order)
+	assert.Equal(t, "changed ok", response.Body)
+	assert.Equal(t, isuphttp.VerdictUp, response.Verdict)
+	assert.Equal(t, "second", transport.GetCalls()[0].Headers.Get("X-Trace"))
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	copied := HTTPClient
+
+	HTTPClient.Use(func(ctx context.Context, request isuphttp.HTTPRequest, next isuphttp.Handler) isuphttp.HTTPResponse {
+		return isuphttp.HTTPResponse{Method: request.GetMethod(), StatusCode: http.StatusTeapot}
+	})
+
+	assert.Equal(t, http.StatusTeapot, HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local")).StatusCode)
+	assert.Empty(t, transport.GetCalls())
+
+	// Middlewares added to a client don't change its copies
+	assert.Equal(t, http.StatusNotFound, copied.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local")).StatusCode)
+}
+
+func TestBuiltInMiddlewares(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+	transport.AddMockRule(isuphttp.MockRule{URLGlob: "https://api.local/*", Response: isuphttp.HTTPResponse{StatusCode: http.StatusOK}})
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/down", Err: context.DeadlineExceeded})
+
+	var logs bytes.Buffer
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.Use(
+		isuphttp.RequestIDMiddleware(""),
+		isuphttp.RequestIDMiddleware("X-Correlation-Id"),
+		isuphttp.UserAgentMiddleware("isup/1.0"),
+		isuphttp.LoggingMiddleware(log.New(&logs, "", 0)),
+	)
+
+	HTTPClient.ParallelRequests([]isuphttp.HTTPRequest{
+		isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/a"),
+		isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/b").
+			SetHeaderValue("X-Request-Id", "fixed").
+			SetHeaderValue("User-Agent", "custom"),
+	})
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/c"))
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/down"))
+
+	calls := map[string]http.Header{}
+	for _, call := range transport.GetCalls() {
+		calls[call.URL] = call.Headers
+	}
+
+	assert.Len(t, calls["https://api.local/a"].Get("X-Request-Id"), 32)
+	assert.Len(t, calls["https://api.local/a"].Get("X-Correlation-Id"), 32)
+	assert.NotEqual(t, calls["https://api.local/a"].Get("X-Request-Id"), calls["https://api.local/c"].Get("X-Request-Id"))
+	assert.Equal(t, "isup/1.0", calls["https://api.local/a"].Get("User-Agent"))
+
+	assert.Equal(t, "fixed", calls["https://api.local/b"].Get("X-Request-Id"))
+	assert.Equal(t, "custom", calls["https://api.local/b"].Get("User-Agent"))
+
+	assert.Contains(t, logs.String(), "GET https://api.local/a 200 ")
+	assert.Contains(t, logs.String(), "GET https://api.local/b 200 ")
+	assert.Contains(t, logs.String(), "GET https://api.local/c 200 ")
+	assert.Contains(t, logs.String(), "GET https://api.local/down 1 0ms error: Request Timeout")
+}
+
+func TestMiddlewaresDontChangeTheCallerRequest(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.Use(isuphttp.RequestIDMiddleware(""), isuphttp.UserAgentMiddleware("isup/1.0"))
+
+	base := isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/a").SetHeaderValue("Accept", isuphttp.ApplicationJSON)
+
+	requests := make([]isuphttp.HTTPRequest, 20)
+	for index := range requests {
+		requests[index] = base
+	}
+
+	HTTPClient.ParallelRequests(requests)
+	HTTPClient.HTTPCall(base)
+
+	ids := map[string]bool{}
+	for _, call := range transport.GetCalls() {
+		ids[call.Headers.Get("X-Request-Id")] = true
+	}
+
+	assert.Len(t, ids, len(requests)+1)
+	assert.Nil(t, base.GetHeaderValue("X-Request-Id"))
+	assert.Nil(t, base.GetHeaderValue("User-Agent"))
+}
@@ -0,0 +1,444 @@
+package isuphttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockRule A rule that gives a mock response to the requests it matches
+// Method is the request method, empty or "*" matches any method
+// URL matches the url exactly, URLGlob with * wildcards and URLRegex with a regular expression
+// The url is matched without its query string, unless the URL or URLGlob has one
+// Every Query, Headers and BodyFields value must be in the request
+// Rules are checked by Priority, higher first, then the most specific rule wins
+// Responses is a sequence of responses given one for each call, the last one is repeated,
+// without it Response is given to every call
+// Delay is waited before answering, up to the request timeout, and Err, like a x509.UnknownAuthorityError, fails the call as a network error
+type MockRule struct {
+	Method     string
+	URL        string
+	URLGlob    string
+	URLRegex   string
+	Query      map[string]string
+	Headers    map[string]string
+	BodyFields map[string]interface{}
+	Priority   int
+	Response   HTTPResponse
+	Responses  []HTTPResponse
+	Delay      time.Duration
+	Err        error
+}
+
+// Error of a call that doesn't match any mock rule
+var errNoMockRule = errors.New("no mock rule matches the call")
+
+// MockCall A request received by the mock
+type MockCall struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    map[string]interface{}
+	Matched bool
+
+	request mockRequest
+}
+
+// TestingT The part of *testing.T used to report mock failures
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// mockRule A MockRule ready to match requests
+type mockRule struct {
+	MockRule
+	urlPattern  *regexp.Regexp
+	specificity int
+	order       int
+	calls       int
+}
+
+// mockAnswer The answer of a mock rule to a call
+type mockAnswer struct {
+	response HTTPResponse
+	delay    time.Duration
+	err      error
+}
+
+// mockRequest The request values matched by the mock rules
+type mockRequest struct {
+	method  string
+	url     string
+	query   url.Values
+	headers http.Header
+	body    map[string]interface{}
+}
+
+// mockRegistry The mock rules of a client and the calls it received
+// In strict mode, calls that don't match a rule fail the test
+type mockRegistry struct {
+	mu     sync.Mutex
+	rules  []*mockRule
+	added  int
+	calls  []MockCall
+	strict TestingT
+}
+
+// newMockRequest Get the values matched by the mock rules from a request
+func newMockRequest(request HTTPRequest) mockRequest {
+	headers := http.Header{}
+	for name, value := range request.headers {
+		headers.Set(name, fmt.Sprintf("%v", value))
+	}
+
+	fullURL := request.getURLWithQueryParans()
+
+	_, rawQuery, _ := strings.Cut(strings.SplitN(fullURL, "#", 2)[0], "?")
+	query, _ := url.ParseQuery(rawQuery)
+
+	return mockRequest{
+		method:  request.method,
+		url:     fullURL,
+		query:   query,
+		headers: headers,
+		body:    request.body,
+	}
+}
+
+// compileMockRule Get a rule ready to match requests
+func compileMockRule(rule MockRule) (*mockRule, error) {
+	compiled := &mockRule{MockRule: rule}
+
+	switch {
+	case rule.URLRegex != "":
+		pattern, err := regexp.Compile(rule.URLRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mock url regex %q: %w", rule.URLRegex, err)
+		}
+
+		compiled.urlPattern = pattern
+		compiled.specificity = 2
+	case rule.URLGlob != "":
+		compiled.urlPattern = globPattern(rule.URLGlob)
+		compiled.specificity = 2
+	case rule.URL != "":
+		compiled.specificity = 4
+	}
+
+	if rule.Method != "" && rule.Method != "*" {
+		compiled.specificity++
+	}
+
+	compiled.specificity += len(rule.Query) + len(rule.Headers) + len(rule.BodyFields)
+
+	return compiled, nil
+}
+
+// add Add a rule, replacing a rule with the same matchers if replace is true
+func (m *mockRegistry) add(rule MockRule, replace bool) error {
+	compiled, err := compileMockRule(rule)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.added++
+	compiled.order = m.added
+
+	if replace {
+		for index, existing := range m.rules {
+			if existing.sameMatchers(rule) {
+				compiled.order = existing.order
+				m.rules[index] = compiled
+
+				return nil
+			}
+		}
+	}
+
+	m.rules = append(m.rules, compiled)
+
+	sort.SliceStable(m.rules, func(i, j int) bool {
+		a, b := m.rules[i], m.rules[j]
+
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+
+		if a.specificity != b.specificity {
+			return a.specificity > b.specificity
+		}
+
+		return a.order < b.order
+	})
+
+	return nil
+}
+
+// respond Record a call and get the answer of the first rule that matches it
+// If no rule matches the call it returns false, and if the registry is strict
+func (m *mockRegistry) respond(request mockRequest) (mockAnswer, bool, bool) {
+	if m == nil {
+		return mockAnswer{}, false, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	call := MockCall{Method: request.method, URL: request.url, Headers: request.headers, Body: request.body, request: request}
+
+	for _, rule := range m.rules {
+		if rule.matches(request) {
+			call.Matched = true
+			m.calls = append(m.calls, call)
+
+			return mockAnswer{response: rule.nextResponse(), delay: rule.Delay, err: rule.Err}, true, false
+		}
+	}
+
+	m.calls = append(m.calls, call)
+
+	if m.strict != nil {
+		m.strict.Errorf("isuphttp: %v: %s %s", errNoMockRule, request.method, request.url)
+	}
+
+	return mockAnswer{}, false, m.strict != nil
+}
+
+// lookup Get the response the first rule that matches a request would give, without recording the call
+func (m *mockRegistry) lookup(request mockRequest) (HTTPResponse, bool) {
+	if m == nil {
+		return HTTPResponse{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.rules {
+		if rule.matches(request) {
+			return rule.response(rule.calls + 1), true
+		}
+	}
+
+	return HTTPResponse{}, false
+}
+
+func (m *mockRegistry) setStrict(t TestingT) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.strict = t
+}
+
+// getCalls Get a copy of the recorded calls
+func (m *mockRegistry) getCalls() []MockCall {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]MockCall(nil), m.calls...)
+}
+
+// countCalls Count the recorded calls that match a rule, 0 if the rule is invalid
+func (m *mockRegistry) countCalls(rule MockRule) int {
+	compiled, err := compileMockRule(rule)
+
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+
+	for _, call := range m.getCalls() {
+		if compiled.matches(call.request) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// assertCalls Check the number of recorded calls that match a rule, failing the test t if it isn't times
+func (m *mockRegistry) assertCalls(t TestingT, rule MockRule, times int) bool {
+	if _, err := compileMockRule(rule); err != nil {
+		t.Errorf("isuphttp: %v", err)
+		return false
+	}
+
+	if count := m.countCalls(rule); count != times {
+		t.Errorf("isuphttp: expected %d mock calls matching %s %s%s%s, got %d", times, rule.Method, rule.URL, rule.URLGlob, rule.URLRegex, count)
+		return false
+	}
+
+	return true
+}
+
+// resetCalls Forget the recorded calls and restart the response sequences
+func (m *mockRegistry) resetCalls() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = nil
+
+	for _, rule := range m.rules {
+		rule.calls = 0
+	}
+}
+
+// wait Wait the answer delay, returning the context error if it is done first
+func (a mockAnswer) wait(ctx context.Context) error {
+	if a.delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(a.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get the next response of the rule sequence
+func (r *mockRule) nextResponse() HTTPResponse {
+	r.calls++
+
+	return r.response(r.calls)
+}
+
+// Get the response of a call to the rule, the last one of the sequence is repeated
+func (r *mockRule) response(call int) HTTPResponse {
+	if len(r.Responses) == 0 {
+		return r.Response
+	}
+
+	if call > len(r.Responses) {
+		return r.Responses[len(r.Responses)-1]
+	}
+
+	return r.Responses[call-1]
+}
+
+// If the rule matches the request
+func (r *mockRule) matches(request mockRequest) bool {
+	if r.Method != "" && r.Method != "*" && !strings.EqualFold(r.Method, request.method) {
+		return false
+	}
+
+	if !r.matchesURL(request.url) {
+		return false
+	}
+
+	for name, value := range r.Query {
+		if !containsString(request.query[name], value) {
+			return false
+		}
+	}
+
+	for name, value := range r.Headers {
+		if !containsString(request.headers.Values(name), value) {
+			return false
+		}
+	}
+
+	for name, value := range r.BodyFields {
+		actual, ok := request.body[name]
+		if !ok || !equalJSONValues(value, actual) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *mockRule) matchesURL(requestURL string) bool {
+	pattern := r.URL
+	if r.URLGlob != "" {
+		pattern = r.URLGlob
+	}
+
+	// Rules without a query string match the url without its query string
+	if !strings.Contains(pattern, "?") || r.URLRegex != "" {
+		requestURL = strings.SplitN(strings.SplitN(requestURL, "#", 2)[0], "?", 2)[0]
+	}
+
+	switch {
+	case r.urlPattern != nil:
+		return r.urlPattern.MatchString(requestURL)
+	case r.URL != "":
+		return r.URL == requestURL
+	}
+
+	return true
+}
+
+// If the rule has the same matchers of a MockRule
+func (r *mockRule) sameMatchers(rule MockRule) bool {
+	return r.Method == rule.Method &&
+		r.URL == rule.URL &&
+		r.URLGlob == rule.URLGlob &&
+		r.URLRegex == rule.URLRegex &&
+		r.Priority == rule.Priority &&
+		reflect.DeepEqual(r.Query, rule.Query) &&
+		reflect.DeepEqual(r.Headers, rule.Headers) &&
+		reflect.DeepEqual(r.BodyFields, rule.BodyFields)
+}
+
+// Convert a glob with * wildcards to an anchored regular expression
+func globPattern(glob string) *regexp.Regexp {
+	var pattern strings.Builder
+
+	pattern.WriteString("^")
+
+	for _, c := range glob {
+		switch c {
+		case '*':
+			pattern.WriteString(".*")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	pattern.WriteString("$")
+
+	return regexp.MustCompile(pattern.String())
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// If two values are the same once encoded as JSON, so 1 and 1.0 are equal
+func equalJSONValues(a interface{}, b interface{}) bool {
+	normalizedA, errA := normalizeJSON(a)
+	normalizedB, errB := normalizeJSON(b)
+
+	return errA == nil && errB == nil && reflect.DeepEqual(normalizedA, normalizedB)
+}
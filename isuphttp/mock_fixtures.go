@@ -0,0 +1,322 @@
+package isuphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidMockFixture Error of a mock fixture file that can't be loaded
+var ErrInvalidMockFixture = errors.New("invalid mock fixture")
+
+// mockFixture A mock rule in a fixture file
+// A fixture file has one fixture or a list of them, in JSON or YAML:
+//
+//	request:
+//	  method: GET
+//	  url: https://api.local/users    # or url_glob, url_regex
+//	  query: {page: "1"}
+//	  headers: {Authorization: Bearer token}
+//	  body: {name: isup}              # body fields
+//	priority: 1
+//	response:
+//	  status: 200
+//	  headers: {Content-Type: application/json}
+//	  body: {id: 7}                   # a string or a value encoded as JSON
+//	  body_file: bodies/user.json     # or the body from a file, relative to the fixture
+//	  delay: 150ms
+type mockFixture struct {
+	Request  mockFixtureRequest  `yaml:"request"`
+	Priority int                 `yaml:"priority"`
+	Response mockFixtureResponse `yaml:"response"`
+}
+
+type mockFixtureRequest struct {
+	Method   string                 `yaml:"method"`
+	URL      string                 `yaml:"url"`
+	URLGlob  string                 `yaml:"url_glob"`
+	URLRegex string                 `yaml:"url_regex"`
+	Query    map[string]string      `yaml:"query"`
+	Headers  map[string]string      `yaml:"headers"`
+	Body     map[string]interface{} `yaml:"body"`
+}
+
+type mockFixtureResponse struct {
+	Status   int                    `yaml:"status"`
+	Headers  map[string]interface{} `yaml:"headers"`
+	Body     interface{}            `yaml:"body"`
+	BodyFile string                 `yaml:"body_file"`
+	Delay    string                 `yaml:"delay"`
+}
+
+// LoadMockFixtures Add the mock rules of the .json, .yaml and .yml files in a directory
+// Files are loaded in name order and subdirectories are not read, so they can hold the body files
+// If a fixture is invalid no rule is added and the error has its file and field
+func (c *HTTPClient) LoadMockFixtures(dir string) error {
+	rules, err := loadMockFixtures(dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		c.getMockRegistry().add(rule, false)
+	}
+
+	return nil
+}
+
+// LoadFixtures Add the mock rules of the .json, .yaml and .yml files in a directory, like HTTPClient.LoadMockFixtures
+func (m *MockTransport) LoadFixtures(dir string) error {
+	rules, err := loadMockFixtures(dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		m.mocks.add(rule, false)
+	}
+
+	return nil
+}
+
+// loadMockFixtures Read the mock rules of the fixture files in a directory
+func loadMockFixtures(dir string) ([]MockRule, error) {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMockFixture, err)
+	}
+
+	rules := []MockRule{}
+
+	// ReadDir gives the entries sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		fileRules, err := loadMockFixtureFile(filepath.Join(dir, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, fileRules...)
+	}
+
+	return rules, nil
+}
+
+// loadMockFixtureFile Read the mock rules of a fixture file
+func loadMockFixtureFile(path string) ([]MockRule, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMockFixture, err)
+	}
+
+	fixtureError := func(field string, err error) error {
+		if field == "" {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidMockFixture, path, err)
+		}
+
+		return fmt.Errorf("%w: %s: %s: %v", ErrInvalidMockFixture, path, field, err)
+	}
+
+	// JSON is valid YAML, so both are read by the YAML decoder
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fixtureError("", err)
+	}
+
+	if len(document.Content) == 0 {
+		return nil, fixtureError("", errors.New("empty file"))
+	}
+
+	list := document.Content[0].Kind == yaml.SequenceNode
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var fixtures []mockFixture
+
+	if list {
+		err = decoder.Decode(&fixtures)
+	} else {
+		fixtures = make([]mockFixture, 1)
+		err = decoder.Decode(&fixtures[0])
+	}
+
+	if err != nil {
+		return nil, fixtureError("", err)
+	}
+
+	rules := make([]MockRule, 0, len(fixtures))
+
+	for index, fixture := range fixtures {
+		prefix := ""
+		if list {
+			prefix = fmt.Sprintf("[%d].", index)
+		}
+
+		rule, field, err := fixture.toMockRule(filepath.Dir(path))
+
+		if err != nil {
+			return nil, fixtureError(prefix+field, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// toMockRule Get the mock rule of a fixture, or the field that makes it invalid
+func (f mockFixture) toMockRule(dir string) (MockRule, string, error) {
+	urls := 0
+	for _, value := range []string{f.Request.URL, f.Request.URLGlob, f.Request.URLRegex} {
+		if value != "" {
+			urls++
+		}
+	}
+
+	if urls != 1 {
+		return MockRule{}, "request.url", errors.New("exactly one of url, url_glob or url_regex is required")
+	}
+
+	rule := MockRule{
+		Method:     strings.ToUpper(f.Request.Method),
+		URL:        f.Request.URL,
+		URLGlob:    f.Request.URLGlob,
+		URLRegex:   f.Request.URLRegex,
+		Query:      f.Request.Query,
+		Headers:    f.Request.Headers,
+		BodyFields: f.Request.Body,
+		Priority:   f.Priority,
+	}
+
+	if _, err := compileMockRule(rule); err != nil {
+		return MockRule{}, "request.url_regex", err
+	}
+
+	response := HTTPResponse{StatusCode: f.Response.Status}
+
+	if response.StatusCode == 0 {
+		response.StatusCode = 200
+	}
+
+	if response.StatusCode < 100 || response.StatusCode > 999 {
+		return MockRule{}, "response.status", fmt.Errorf("%d is not a http status code", f.Response.Status)
+	}
+
+	if len(f.Response.Headers) > 0 {
+		response.Headers = make(map[string]interface{}, len(f.Response.Headers))
+	}
+
+	for name, value := range f.Response.Headers {
+		values, ok := fixtureHeaderValues(value)
+
+		if !ok {
+			return MockRule{}, "response.headers." + name, errors.New("must be a string or a list of strings")
+		}
+
+		response.Headers[http.CanonicalHeaderKey(name)] = values
+	}
+
+	if contentType := response.GetHeader("Content-Type"); contentType != "" {
+		response.ContentType, response.ContentTypeParams = parseContentType(contentType)
+	}
+
+	switch {
+	case f.Response.Body != nil && f.Response.BodyFile != "":
+		return MockRule{}, "response.body_file", errors.New("body and body_file can't be used together")
+	case f.Response.BodyFile != "":
+		bodyFile := f.Response.BodyFile
+		if !filepath.IsAbs(bodyFile) {
+			bodyFile = filepath.Join(dir, bodyFile)
+		}
+
+		body, err := os.ReadFile(bodyFile)
+
+		if err != nil {
+			return MockRule{}, "response.body_file", err
+		}
+
+		response.Body = string(body)
+	case f.Response.Body != nil:
+		if body, ok := f.Response.Body.(string); ok {
+			response.Body = body
+			break
+		}
+
+		body, err := json.Marshal(f.Response.Body)
+
+		if err != nil {
+			return MockRule{}, "response.body", err
+		}
+
+		response.Body = string(body)
+	}
+
+	response.ContentLength = int64(len(response.Body))
+
+	rule.Response = response
+
+	if f.Response.Delay != "" {
+		delay, err := time.ParseDuration(f.Response.Delay)
+
+		if err != nil {
+			return MockRule{}, "response.delay", err
+		}
+
+		if delay < 0 {
+			return MockRule{}, "response.delay", errors.New("must not be negative")
+		}
+
+		rule.Delay = delay
+	}
+
+	return rule, "", nil
+}
+
+// fixtureHeaderValues Get the values of a fixture response header
+func fixtureHeaderValues(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case int, float64, bool:
+		return []string{fmt.Sprintf("%v", v)}, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+
+		for _, item := range v {
+			text, ok := item.(string)
+
+			if !ok {
+				return nil, false
+			}
+
+			values = append(values, text)
+		}
+
+		return values, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,120 @@
+package isuphttp_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMockFixtures(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+
+	assert.Nil(t, HTTPClient.LoadMockFixtures("testdata/mock_fixtures"))
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/users/7"))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "{\"id\":7,\"name\":\"isup\"}\n", response.Body)
+	assert.Equal(t, isuphttp.ApplicationJSON, response.ContentType)
+	assert.Equal(t, "utf-8", response.ContentTypeParams["charset"])
+	assert.Equal(t, []string{"a=1", "b=2"}, response.GetHeaderValues("Set-Cookie"))
+
+	request := isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/users").
+		SetAuthorization("Bearer token").
+		SetBody(map[string]interface{}{"name": "isup"})
+
+	response = HTTPClient.HTTPCall(request)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.JSONEq(t, `{"id":8,"name":"isup"}`, response.Body)
+
+	response = HTTPClient.HTTPCall(request.SetAuthorization("Bearer other"))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	start := time.Now()
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/health/live"))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "ok", response.Body)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestMockTransportLoadFixtures(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	assert.Nil(t, transport.LoadFixtures("testdata/mock_fixtures"))
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/users").
+		SetAuthorization("Bearer token").
+		SetBody(map[string]interface{}{"name": "isup"}))
+
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, isuphttp.ApplicationJSON, response.ContentType)
+	assert.JSONEq(t, `{"id":8,"name":"isup"}`, response.Body)
+}
+
+func TestLoadMockFixturesErrors(t *testing.T) {
+	var tests = []struct {
+		file          string
+		content       string
+		expectedError string
+	}{
+		{"bad.yaml", "request: [", "bad.yaml: yaml:"},
+		{"empty.yaml", "", "empty.yaml: empty file"},
+		{"unknown.yaml", "request:\n  url: https://api.local\n  methd: GET\n", "unknown.yaml: yaml: unmarshal errors:\n  line 3: field methd not found"},
+		{"no_url.json", `{"request": {"method": "GET"}}`, "no_url.json: request.url: exactly one of url, url_glob or url_regex is required"},
+		{"two_urls.json", `{"request": {"url": "https://a", "url_glob": "https://*"}}`, "two_urls.json: request.url:"},
+		{"regex.yaml", "request:\n  url_regex: '('\n", "regex.yaml: request.url_regex: invalid mock url regex"},
+		{"status.yaml", "request:\n  url: https://a\nresponse:\n  status: 42\n", "status.yaml: response.status: 42 is not a http status code"},
+		{"status_type.yaml", "request:\n  url: https://a\nresponse:\n  status: ok\n", "status_type.yaml: yaml: unmarshal errors:\n  line 4: cannot unmarshal !!str `ok` into int"},
+		{"header.yaml", "request:\n  url: https://a\nresponse:\n  headers:\n    X-List: {a: b}\n", "header.yaml: response.headers.X-List: must be a string or a list of strings"},
+		{"both.yaml", "request:\n  url: https://a\nresponse:\n  body: ok\n  body_file: ok.txt\n", "both.yaml: response.body_file: body and body_file can't be used together"},
+		{"missing.yaml", "request:\n  url: https://a\nresponse:\n  body_file: missing.txt\n", "missing.yaml: response.body_file: open "},
+		{"delay.yaml", "request:\n  url: https://a\nresponse:\n  delay: soon\n", "delay.yaml: response.delay: time: invalid duration"},
+		{"list.yaml", "- request:\n    url: https://a\n- request:\n    url: https://b\n  response:\n    delay: -1s\n", "list.yaml: [1].response.delay: must not be negative"},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, test.file)
+
+		assert.Nil(t, os.WriteFile(path, []byte(test.content), 0o600))
+
+		HTTPClient := isuphttp.HTTPClient{}
+		HTTPClient.SetMockEnable(true)
+
+		err := HTTPClient.LoadMockFixtures(dir)
+
+		if assert.Error(t, err, test.file) {
+			assert.True(t, errors.Is(err, isuphttp.ErrInvalidMockFixture))
+			assert.Contains(t, err.Error(), "invalid mock fixture: "+dir+string(filepath.Separator)+test.expectedError)
+		}
+
+		assert.Empty(t, HTTPClient.GetMockCalls())
+	}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	assert.ErrorIs(t, HTTPClient.LoadMockFixtures("testdata/missing"), isuphttp.ErrInvalidMockFixture)
+}
+
+func TestLoadMockFixturesIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("request:\n  url: https://api.local/a\n"), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("request:\n  method: GET\n"), 0o600))
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+
+	assert.Error(t, HTTPClient.LoadMockFixtures(dir))
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/a"))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
@@ -0,0 +1,229 @@
+package isuphttp_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockRules(t *testing.T) {
+	rules := []isuphttp.MockRule{
+		{URLGlob: "localhost:8080/*", Response: isuphttp.HTTPResponse{StatusCode: 1}},
+		{Method: isuphttp.GET, URLGlob: "localhost:8080/users/*", Response: isuphttp.HTTPResponse{StatusCode: 2}},
+		{Method: isuphttp.GET, URLRegex: `^localhost:8080/users/\d+$`, Query: map[string]string{"expand": "true"}, Response: isuphttp.HTTPResponse{StatusCode: 3}},
+		{Method: isuphttp.GET, URL: "localhost:8080/users/42", Response: isuphttp.HTTPResponse{StatusCode: 4}},
+		{Method: "*", URL: "localhost:8080/login", Headers: map[string]string{"X-Tenant": "isup"}, Response: isuphttp.HTTPResponse{StatusCode: 5}},
+		{Method: isuphttp.POST, URL: "localhost:8080/login", BodyFields: map[string]interface{}{"user": "admin", "remember": true}, Response: isuphttp.HTTPResponse{StatusCode: 6}},
+		{URL: "localhost:8080/search?q=isup", Response: isuphttp.HTTPResponse{StatusCode: 7}},
+		{URLGlob: "localhost:8080/maintenance*", Priority: 10, Response: isuphttp.HTTPResponse{StatusCode: 8}},
+	}
+
+	var tests = []struct {
+		request        isuphttp.HTTPRequest
+		expectedStatus int
+	}{
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/health"), 1},
+		{isuphttp.GetHTTPRequest(isuphttp.DELETE, "localhost:8080/users/7"), 1},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users/7"), 2},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users/7?expand=true"), 3},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users/7").SetQueryParams(map[string]interface{}{"expand": true}), 3},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users/abc?expand=true"), 2},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users/42?expand=true"), 4},
+		{isuphttp.GetHTTPRequest(isuphttp.PUT, "localhost:8080/login").SetHeaderValue("x-tenant", "isup"), 5},
+		{isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/login").SetBody(map[string]interface{}{"user": "admin", "remember": true, "pass": "x"}), 6},
+		{isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/login").SetBody(map[string]interface{}{"user": "guest", "remember": true}), 1},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/search").SetQueryParams(map[string]interface{}{"q": "isup"}), 7},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/search?q=other"), 1},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/maintenance/users/42"), 8},
+		{isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:9090/health"), 404},
+	}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+
+	for _, rule := range rules {
+		assert.Nil(t, HTTPClient.AddMockRule(rule))
+	}
+
+	for _, test := range tests {
+		response := HTTPClient.HTTPCall(test.request)
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode)
+	}
+}
+
+func TestMockRuleInvalidRegex(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+
+	err := HTTPClient.AddMockRule(isuphttp.MockRule{URLRegex: "("})
+
+	assert.NotNil(t, err)
+}
+
+func TestAddMockResponseReplace(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+
+	HTTPClient.AddMockResponse(isuphttp.HTTPResponse{StatusCode: 200}, isuphttp.GET, "localhost:8080/api")
+	HTTPClient.AddMockResponse(isuphttp.HTTPResponse{StatusCode: 500}, isuphttp.GET, "localhost:8080/api")
+
+	assert.Equal(t, 500, HTTPClient.GetMockResponse(isuphttp.GET, "localhost:8080/api").StatusCode)
+}
+
+type fakeTestingT struct {
+	errors []string
+}
+
+func (f *fakeTestingT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockResponseSequence(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+
+	HTTPClient.AddMockRule(isuphttp.MockRule{
+		URL: "localhost:8080/api",
+		Responses: []isuphttp.HTTPResponse{
+			{StatusCode: isuphttp.StatusTimeout},
+			{StatusCode: 503},
+			{StatusCode: 200},
+		},
+	})
+
+	request := isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/api")
+
+	for _, expectedStatus := range []int{isuphttp.StatusTimeout, 503, 200, 200} {
+		assert.Equal(t, expectedStatus, HTTPClient.HTTPCall(request).StatusCode)
+	}
+
+	HTTPClient.ResetMockCalls()
+
+	assert.Equal(t, isuphttp.StatusTimeout, HTTPClient.HTTPCall(request).StatusCode)
+}
+
+// GetMockResponse only looks the response up
+func TestGetMockResponseLookup(t *testing.T) {
+	fake := &fakeTestingT{}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+	HTTPClient.SetMockStrict(fake)
+
+	HTTPClient.AddMockRule(isuphttp.MockRule{
+		URL:   "localhost:8080/api",
+		Delay: time.Second,
+		Responses: []isuphttp.HTTPResponse{
+			{StatusCode: 503},
+			{StatusCode: 200},
+		},
+	})
+
+	start := time.Now()
+	assert.Equal(t, 503, HTTPClient.GetMockResponse(isuphttp.GET, "localhost:8080/api").StatusCode)
+	assert.Equal(t, 503, HTTPClient.GetMockResponse(isuphttp.GET, "localhost:8080/api").StatusCode)
+	assert.Equal(t, 404, HTTPClient.GetMockResponse(isuphttp.GET, "localhost:8080/missing").StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.Empty(t, HTTPClient.GetMockCalls())
+	assert.Empty(t, fake.errors)
+
+	HTTPClient.AddMockRule(isuphttp.MockRule{URL: "localhost:8080/api", Priority: 1, Response: isuphttp.HTTPResponse{StatusCode: 201}})
+	assert.Equal(t, 201, HTTPClient.GetMockResponse(isuphttp.GET, "localhost:8080/api").StatusCode)
+}
+
+// Mock sequences test the retry policy
+func TestMockResponseSequenceRetry(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+
+	HTTPClient.AddMockRule(isuphttp.MockRule{
+		URL:       "localhost:8080/api",
+		Responses: []isuphttp.HTTPResponse{{StatusCode: 503}, {StatusCode: 503}, {StatusCode: 200}},
+	})
+
+	policy := isuphttp.GetDefaultRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/api").SetRetryPolicy(policy))
+
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, 3, response.Attempts)
+	HTTPClient.AssertMockCalls(t, isuphttp.MockRule{URL: "localhost:8080/api"}, 3)
+}
+
+func TestMockDelayTimeOut(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+	HTTPClient.AddMockRule(isuphttp.MockRule{URL: "localhost:8080/slow", Delay: 100 * time.Millisecond, Response: isuphttp.HTTPResponse{StatusCode: 200}})
+	HTTPClient.AddMockRule(isuphttp.MockRule{URL: "localhost:8080/hanging", Delay: time.Minute, Response: isuphttp.HTTPResponse{StatusCode: 200}})
+
+	start := time.Now()
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/slow"))
+	assert.Equal(t, 200, response.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// A delay longer than the request timeout times out
+	start = time.Now()
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/hanging").SetTimeOut(100))
+	assert.Equal(t, isuphttp.StatusTimeout, response.StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestMockCallsRecording(t *testing.T) {
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+	HTTPClient.AddMockRule(isuphttp.MockRule{URLGlob: "localhost:8080/*", Response: isuphttp.HTTPResponse{StatusCode: 200}})
+
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, "localhost:8080/users").SetBody(map[string]interface{}{"name": "isup"}))
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/users").SetQueryParams(map[string]interface{}{"page": 2}))
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:9090/other").SetAuthorization("Bearer x"))
+
+	calls := HTTPClient.GetMockCalls()
+
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, isuphttp.POST, calls[0].Method)
+		assert.Equal(t, map[string]interface{}{"name": "isup"}, calls[0].Body)
+		assert.Equal(t, "localhost:8080/users?page=2", calls[1].URL)
+		assert.Equal(t, "Bearer x", calls[2].Headers.Get("Authorization"))
+		assert.True(t, calls[0].Matched)
+		assert.False(t, calls[2].Matched)
+	}
+
+	assert.Equal(t, 2, HTTPClient.CountMockCalls(isuphttp.MockRule{URL: "localhost:8080/users"}))
+	assert.Equal(t, 1, HTTPClient.CountMockCalls(isuphttp.MockRule{Method: isuphttp.GET, URL: "localhost:8080/users", Query: map[string]string{"page": "2"}}))
+	assert.Equal(t, 1, HTTPClient.CountMockCalls(isuphttp.MockRule{BodyFields: map[string]interface{}{"name": "isup"}}))
+
+	fake := &fakeTestingT{}
+
+	assert.True(t, HTTPClient.AssertMockCalls(fake, isuphttp.MockRule{Method: isuphttp.POST}, 1))
+	assert.False(t, HTTPClient.AssertMockCalls(fake, isuphttp.MockRule{Method: isuphttp.DELETE}, 1))
+	assert.Len(t, fake.errors, 1)
+}
+
+func TestMockStrict(t *testing.T) {
+	fake := &fakeTestingT{}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetMockEnable(true)
+	HTTPClient.SetMockStrict(fake)
+	HTTPClient.AddMockResponse(isuphttp.HTTPResponse{StatusCode: 200}, isuphttp.GET, "localhost:8080/api")
+
+	assert.Equal(t, 200, HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/api")).StatusCode)
+	assert.Empty(t, fake.errors)
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/other"))
+
+	assert.Equal(t, 404, response.StatusCode)
+	assert.NotEmpty(t, response.Error)
+	assert.Len(t, fake.errors, 1)
+
+	HTTPClient.SetMockStrict(nil)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "localhost:8080/other"))
+
+	assert.Empty(t, response.Error)
+	assert.Len(t, fake.errors, 1)
+}
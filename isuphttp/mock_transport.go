@@ -0,0 +1,203 @@
+package isuphttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MockTransport A http.RoundTripper that answers with mock responses
+// Set in a client with SetTransport, calls go through the real request building and
+// response reading, so headers, body and query parameters encoding are tested too.
+// The rules Delay simulates latency and timeouts and the rules Err network and TLS errors.
+type MockTransport struct {
+	mocks *mockRegistry
+}
+
+// GetMockTransport Instantiate a mock transport without rules
+func GetMockTransport() *MockTransport {
+	return &MockTransport{mocks: &mockRegistry{}}
+}
+
+// AddMockResponse Add a mock response for a api call
+func (m *MockTransport) AddMockResponse(expectedResponse HTTPResponse, apiMethod string, apiURL string) {
+	m.mocks.add(MockRule{Method: apiMethod, URL: apiURL, Response: expectedResponse}, true)
+}
+
+// AddMockRule Add a rule that gives a mock response to the api calls it matches
+func (m *MockTransport) AddMockRule(rule MockRule) error {
+	return m.mocks.add(rule, false)
+}
+
+// SetStrict Make calls that don't match a mock rule fail the test t and get an error, nil disables it
+// Without it they get a 404 response
+func (m *MockTransport) SetStrict(t TestingT) {
+	m.mocks.setStrict(t)
+}
+
+// GetCalls Get the calls received by the transport, in order
+func (m *MockTransport) GetCalls() []MockCall {
+	return m.mocks.getCalls()
+}
+
+// CountCalls Count the calls received by the transport that match a rule
+func (m *MockTransport) CountCalls(rule MockRule) int {
+	return m.mocks.countCalls(rule)
+}
+
+// AssertCalls Check that the transport received times calls that match a rule, failing the test t if not
+func (m *MockTransport) AssertCalls(t TestingT, rule MockRule, times int) bool {
+	return m.mocks.assertCalls(t, rule, times)
+}
+
+// ResetCalls Forget the calls received by the transport and restart the response sequences
+func (m *MockTransport) ResetCalls() {
+	m.mocks.resetCalls()
+}
+
+// RoundTrip Answer a request with the response of the first rule that matches it
+func (m *MockTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	call, err := newMockRequestFromGo(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	answer, matched, strict := m.mocks.respond(call)
+
+	if !matched {
+		if strict {
+			return nil, errNoMockRule
+		}
+
+		return toGoHTTPResponse(HTTPResponse{StatusCode: http.StatusNotFound, Body: errNoMockRule.Error()}, request), nil
+	}
+
+	if err := answer.wait(request.Context()); err != nil {
+		return nil, err
+	}
+
+	if answer.err != nil {
+		return nil, answer.err
+	}
+
+	return toGoHTTPResponse(answer.response, request), nil
+}
+
+// SetTransport Set the http.RoundTripper used by the client calls, like a MockTransport
+// Without it the client uses its pooled transports
+func (c *HTTPClient) SetTransport(transport http.RoundTripper) {
+	c.transport = transport
+}
+
+// Get the values matched by the mock rules from a go http.Request
+// JSON and form bodies are decoded to be matched by the rules BodyFields
+func newMockRequestFromGo(request *http.Request) (mockRequest, error) {
+	call := mockRequest{
+		method:  request.Method,
+		url:     request.URL.String(),
+		query:   request.URL.Query(),
+		headers: request.Header.Clone(),
+	}
+
+	if request.Body == nil || request.Body == http.NoBody {
+		return call, nil
+	}
+
+	// A RoundTripper must not change the request, so the body is read from a copy when it can be
+	reader := request.Body
+	if request.GetBody != nil {
+		request.Body.Close()
+
+		bodyCopy, err := request.GetBody()
+
+		if err != nil {
+			return call, err
+		}
+
+		reader = bodyCopy
+	}
+
+	body, err := io.ReadAll(reader)
+	reader.Close()
+
+	if err != nil {
+		return call, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	// Bodies that can't be decoded just don't match BodyFields
+	switch mediaType {
+	case ApplicationJSON:
+		json.Unmarshal(body, &call.body)
+	case FormURLEncoded:
+		call.body, _ = parseFormBody(body)
+	}
+
+	return call, nil
+}
+
+// Decode a form body, fields with one value are strings and repeated fields are []string
+func parseFormBody(body []byte) (map[string]interface{}, error) {
+	form, err := url.ParseQuery(string(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(form))
+
+	for name, values := range form {
+		if len(values) == 1 {
+			fields[name] = values[0]
+			continue
+		}
+
+		fields[name] = values
+	}
+
+	return fields, nil
+}
+
+// Convert a HTTPResponse to the go http.Response of a request
+func toGoHTTPResponse(response HTTPResponse, request *http.Request) *http.Response {
+	header := http.Header{}
+
+	for name, value := range response.Headers {
+		switch v := value.(type) {
+		case []string:
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), v...)
+		default:
+			header.Set(name, fmt.Sprintf("%v", v))
+		}
+	}
+
+	if response.ContentType != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", mime.FormatMediaType(response.ContentType, response.ContentTypeParams))
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,148 @@
+package isuphttp_test
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockTransportFullPipeline(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	transport.AddMockRule(isuphttp.MockRule{
+		Method:     isuphttp.POST,
+		URL:        "https://api.local/users?ids=1&ids=2",
+		Headers:    map[string]string{"Authorization": "Bearer token", "Content-Type": isuphttp.ApplicationJSON},
+		BodyFields: map[string]interface{}{"name": "isup", "active": true},
+		Response: isuphttp.HTTPResponse{
+			StatusCode: http.StatusCreated,
+			Body:       `{"id":7}`,
+			Headers:    map[string]interface{}{"Content-Type": "application/json; charset=utf-8", "Set-Cookie": []string{"a=1", "b=2"}},
+		},
+	})
+	transport.AddMockRule(isuphttp.MockRule{
+		Method:     isuphttp.POST,
+		URL:        "https://api.local/login",
+		BodyFields: map[string]interface{}{"user": "admin"},
+		Response:   isuphttp.HTTPResponse{StatusCode: http.StatusNoContent},
+	})
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	var tests = []struct {
+		request        isuphttp.HTTPRequest
+		expectedStatus int
+	}{
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/users").
+				SetAuthorization("Bearer token").
+				SetQueryParams(map[string]interface{}{"ids": []int{1, 2}}).
+				SetBody(map[string]interface{}{"name": "isup", "active": true}),
+			http.StatusCreated,
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/users").
+				SetAuthorization("Bearer token").
+				SetQueryParams(map[string]interface{}{"ids": []int{1, 2}}).
+				SetQueryArrayStyle(isuphttp.QueryArrayComma).
+				SetBody(map[string]interface{}{"name": "isup", "active": true}),
+			http.StatusNotFound,
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/login").SetFormBody(map[string]interface{}{"user": "admin"}),
+			http.StatusNoContent,
+		},
+		{
+			isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/login").SetFormBody(map[string]interface{}{"user": "guest"}),
+			http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		response := HTTPClient.HTTPCall(test.request)
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode)
+	}
+
+	response := HTTPClient.HTTPCall(tests[0].request)
+
+	assert.Equal(t, `{"id":7}`, response.Body)
+	assert.Equal(t, isuphttp.ApplicationJSON, response.ContentType)
+	assert.Equal(t, "utf-8", response.ContentTypeParams["charset"])
+	assert.Equal(t, []string{"a=1", "b=2"}, response.GetHeaderValues("Set-Cookie"))
+	assert.Len(t, response.Cookies, 2)
+	assert.Equal(t, int64(8), response.ContentLength)
+
+	transport.AssertCalls(t, isuphttp.MockRule{URLGlob: "https://api.local/users*"}, 3)
+}
+
+func TestMockTransportSimulations(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/slow", Delay: 100 * time.Millisecond})
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/hanging", Delay: time.Minute})
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/cert", Err: x509.UnknownAuthorityError{}})
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/expired", Err: x509.CertificateInvalidError{Reason: x509.Expired}})
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/slow"))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.GreaterOrEqual(t, response.ResponseTime, float64(100))
+
+	start := time.Now()
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/hanging").SetTimeOut(100))
+	assert.Equal(t, isuphttp.StatusTimeout, response.StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/cert"))
+	assert.Equal(t, isuphttp.StatusUnknownAuthority, response.StatusCode)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/expired"))
+	assert.Equal(t, isuphttp.StatusCertExpired, response.StatusCode)
+}
+
+func TestMockTransportStrict(t *testing.T) {
+	fake := &fakeTestingT{}
+
+	transport := isuphttp.GetMockTransport()
+	transport.SetStrict(fake)
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/missing"))
+
+	assert.Contains(t, response.Error, "no mock rule matches the call")
+	assert.Len(t, fake.errors, 1)
+	assert.Len(t, transport.GetCalls(), 1)
+
+	// Without strict mode the call gets a 404
+	transport.SetStrict(nil)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/missing"))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Empty(t, response.Error)
+}
+
+func TestMockTransportKeepsRequestBody(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+	transport.AddMockRule(isuphttp.MockRule{URL: "https://api.local/users", BodyFields: map[string]interface{}{"name": "isup"}})
+
+	request, _ := http.NewRequest(isuphttp.POST, "https://api.local/users", strings.NewReader(`{"name":"isup"}`))
+	request.Header.Set("Content-Type", isuphttp.ApplicationJSON)
+	body := request.Body
+
+	response, err := transport.RoundTrip(request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, body == request.Body)
+}
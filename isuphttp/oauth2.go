@@ -0,0 +1,237 @@
+package isuphttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are refreshed this time before they expire
+const tokenRefreshMargin = 10 * time.Second
+
+// Timeout of the token requests
+const tokenTimeOut = 10 * time.Second
+
+// OAuth2ClientCredentials OAuth2 client credentials grant (RFC 6749 section 4.4)
+type OAuth2ClientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	transport    http.RoundTripper
+
+	mu        sync.Mutex
+	token     string
+	tokenType string
+	expiry    time.Time
+	fetching  *tokenFetch
+}
+
+// tokenFetch A token request waited by the calls that need the token
+type tokenFetch struct {
+	done          chan struct{}
+	authorization string
+	err           error
+}
+
+// cancelContext The cancellation of a context without its values, like the call trace
+type cancelContext struct {
+	context.Context
+}
+
+func (cancelContext) Value(key interface{}) interface{} {
+	return nil
+}
+
+// oauth2Token The response of a token endpoint
+type oauth2Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// GetOAuth2ClientCredentials Get a provider that sends a token got from a token endpoint with the client credentials
+// The token is cached and fetched again 10 seconds before it expires, or when a call gets a 401 response.
+// Token requests follow the cancellation of the call and concurrent calls wait for the same token request.
+// They don't use the call transport, so the client secret is only sent with verified TLS.
+func GetOAuth2ClientCredentials(tokenURL string, clientID string, clientSecret string, scopes ...string) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
+
+// SetTransport Set the http.RoundTripper of the token requests, like one trusting a private CA
+// Without it the token requests use http.DefaultTransport
+func (o *OAuth2ClientCredentials) SetTransport(transport http.RoundTripper) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.transport = transport
+}
+
+// Authorize Set the Authorization header with a valid token
+func (o *OAuth2ClientCredentials) Authorize(request *http.Request) error {
+	ctx := request.Context()
+
+	for {
+		o.mu.Lock()
+
+		if o.token != "" && (o.expiry.IsZero() || time.Now().Add(tokenRefreshMargin).Before(o.expiry)) {
+			request.Header.Set("Authorization", o.tokenType+" "+o.token)
+			o.mu.Unlock()
+
+			return nil
+		}
+
+		// Another call is fetching the token
+		if fetch := o.fetching; fetch != nil {
+			o.mu.Unlock()
+
+			select {
+			case <-fetch.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			// A fetch stopped by the context of its own call is started again
+			if errors.Is(fetch.err, context.Canceled) || errors.Is(fetch.err, context.DeadlineExceeded) {
+				continue
+			}
+
+			if fetch.err != nil {
+				return fetch.err
+			}
+
+			request.Header.Set("Authorization", fetch.authorization)
+
+			return nil
+		}
+
+		fetch := &tokenFetch{done: make(chan struct{})}
+		o.fetching = fetch
+		o.mu.Unlock()
+
+		token, err := o.fetchToken(ctx)
+
+		o.mu.Lock()
+		o.fetching = nil
+
+		if err == nil {
+			o.setToken(token)
+			fetch.authorization = o.tokenType + " " + o.token
+		}
+
+		o.mu.Unlock()
+
+		fetch.err = err
+		close(fetch.done)
+
+		if err != nil {
+			return err
+		}
+
+		request.Header.Set("Authorization", fetch.authorization)
+
+		return nil
+	}
+}
+
+// Challenge Drop a cached token refused by the server, so the call is sent again with a new one
+func (o *OAuth2ClientCredentials) Challenge(response *http.Response) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == "" {
+		return false
+	}
+
+	o.token = ""
+
+	return true
+}
+
+// setToken Keep a token got from the token endpoint
+func (o *OAuth2ClientCredentials) setToken(token oauth2Token) {
+	o.token = token.AccessToken
+	o.tokenType = "Bearer"
+	o.expiry = time.Time{}
+
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		o.tokenType = token.TokenType
+	}
+
+	if token.ExpiresIn > 0 {
+		o.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
+
+// fetchToken Get a new token from the token endpoint, with the cancellation of the call
+func (o *OAuth2ClientCredentials) fetchToken(ctx context.Context) (oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	if len(o.scopes) > 0 {
+		form.Set("scope", strings.Join(o.scopes, " "))
+	}
+
+	o.mu.Lock()
+	client := &http.Client{Transport: o.transport, Timeout: tokenTimeOut}
+	o.mu.Unlock()
+
+	// The call trace is left out of the token request context, so it keeps the call timings
+	request, err := http.NewRequestWithContext(cancelContext{ctx}, POST, o.tokenURL, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", FormURLEncoded)
+	request.Header.Set("Accept", ApplicationJSON)
+	request.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+
+	var token oauth2Token
+	decodeErr := json.Unmarshal(body, &token)
+
+	if response.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return oauth2Token{}, fmt.Errorf("oauth2 token request: %d %s %s", response.StatusCode, token.Error, token.ErrorDescription)
+		}
+
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: %d %s", response.StatusCode, http.StatusText(response.StatusCode))
+	}
+
+	if decodeErr != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: invalid response: %w", decodeErr)
+	}
+
+	if token.AccessToken == "" {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request: response without access_token")
+	}
+
+	return token, nil
+}
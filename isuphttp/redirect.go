@@ -0,0 +1,83 @@
+package isuphttp
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Default max number of redirects followed, the same of the go http.Client
+const maxRedirects = 10
+
+var (
+	errTooManyRedirects = errors.New("too many redirects")
+	errInsecureRedirect = errors.New("redirect from https to http")
+)
+
+// RedirectHop A redirect response followed by a call
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+// SetFollowRedirects Set if redirects are followed, default is true
+// When redirects aren't followed the response is the redirect itself
+func (h HTTPRequest) SetFollowRedirects(follow bool) HTTPRequest {
+	h.noRedirects = !follow
+	return h
+}
+
+// SetMaxRedirects Set the max number of redirects followed, default is 10
+func (h HTTPRequest) SetMaxRedirects(max int) HTTPRequest {
+	h.maxRedirects = max
+	return h
+}
+
+// SetBlockInsecureRedirects Set if redirects from https to http fail the call
+func (h HTTPRequest) SetBlockInsecureRedirects(block bool) HTTPRequest {
+	h.blockInsecureRedirects = block
+	return h
+}
+
+// GetMaxRedirects Get the max number of redirects followed
+func (h HTTPRequest) GetMaxRedirects() int {
+	if h.maxRedirects < 1 {
+		return maxRedirects
+	}
+
+	return h.maxRedirects
+}
+
+// redirectRecorder Apply the request redirect policy and record the redirects of a call
+type redirectRecorder struct {
+	request HTTPRequest
+	chain   []RedirectHop
+}
+
+// checkRedirect is the http.Client CheckRedirect of the call
+func (r *redirectRecorder) checkRedirect(next *http.Request, via []*http.Request) error {
+	previous := via[len(via)-1]
+
+	hop := RedirectHop{URL: previous.URL.String()}
+	if next.Response != nil {
+		hop.StatusCode = next.Response.StatusCode
+		hop.Location = next.Response.Header.Get("Location")
+	}
+
+	if r.request.noRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) > r.request.GetMaxRedirects() {
+		return errTooManyRedirects
+	}
+
+	if r.request.blockInsecureRedirects && previous.URL.Scheme == "https" && next.URL.Scheme == "http" {
+		return errInsecureRedirect
+	}
+
+	// Only the followed redirects are in the chain
+	r.chain = append(r.chain, hop)
+
+	return nil
+}
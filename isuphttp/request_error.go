@@ -0,0 +1,98 @@
+package isuphttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// requestErrorStatus Get the status code for a request error
+// It returns 0 if the error don't match any known class
+func requestErrorStatus(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return StatusCanceled
+	}
+
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		if dnsError.IsTimeout {
+			return StatusTimeout
+		}
+
+		return StatusDNSFailure
+	}
+
+	switch {
+	case errors.Is(err, errTooManyRedirects):
+		return StatusTooManyRedirects
+	case errors.Is(err, errInsecureRedirect):
+		return StatusInsecureRedirect
+	}
+
+	if status := certificateErrorStatus(err); status != 0 {
+		return status
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StatusConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return StatusConnectionReset
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return StatusHostUnreachable
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StatusTimeout
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return StatusTimeout
+	}
+
+	var recordHeaderError tls.RecordHeaderError
+	if errors.As(err, &recordHeaderError) {
+		return StatusTLSHandshake
+	}
+
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "remote error" {
+		return StatusTLSHandshake
+	}
+
+	return 0
+}
+
+// Get the status code for a certificate validation error
+func certificateErrorStatus(err error) int {
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		return StatusUnknownAuthority
+	}
+
+	var hostnameError x509.HostnameError
+	if errors.As(err, &hostnameError) {
+		return StatusHostnameMismatch
+	}
+
+	var invalidError x509.CertificateInvalidError
+	if errors.As(err, &invalidError) {
+		if invalidError.Reason == x509.Expired {
+			return StatusCertExpired
+		}
+
+		return StatusInvalidCert
+	}
+
+	var verificationError *tls.CertificateVerificationError
+	if errors.As(err, &verificationError) {
+		return StatusInvalidCert
+	}
+
+	return 0
+}
@@ -0,0 +1,52 @@
+package isuphttp
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRequestErrorStatus(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+
+	var tests = []struct {
+		err            error
+		expectedStatus int
+	}{
+		{errors.New("something else"), 0},
+		{&url.Error{Op: "Get", URL: "https://localhost", Err: context.Canceled}, StatusCanceled},
+		{&url.Error{Op: "Get", URL: "https://localhost", Err: context.DeadlineExceeded}, StatusTimeout},
+		{dial(timeoutError{}), StatusTimeout},
+		{dial(&net.DNSError{Err: "no such host", Name: "isup.invalid", IsNotFound: true}), StatusDNSFailure},
+		{dial(&net.DNSError{Err: "timeout", Name: "isup.invalid", IsTimeout: true}), StatusTimeout},
+		{dial(os.NewSyscallError("connect", syscall.ECONNREFUSED)), StatusConnectionRefused},
+		{dial(os.NewSyscallError("read", syscall.ECONNRESET)), StatusConnectionReset},
+		{dial(os.NewSyscallError("connect", syscall.EHOSTUNREACH)), StatusHostUnreachable},
+		{&url.Error{Op: "Get", URL: "https://localhost", Err: io.EOF}, StatusConnectionReset},
+		{fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), StatusUnknownAuthority},
+		{fmt.Errorf("tls: %w", x509.HostnameError{Host: "localhost", Certificate: &x509.Certificate{}}), StatusHostnameMismatch},
+		{fmt.Errorf("tls: %w", x509.CertificateInvalidError{Reason: x509.Expired}), StatusCertExpired},
+		{fmt.Errorf("tls: %w", x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign}), StatusInvalidCert},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expectedStatus, requestErrorStatus(test.err), test.err.Error())
+	}
+}
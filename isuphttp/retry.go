@@ -0,0 +1,203 @@
+package isuphttp
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy How a failed call is retried
+// MaxAttempts is the max number of attempts, counting the first one
+// Before each retry the call waits a random time (full jitter) up to BaseBackoff * 2^retry, capped by MaxBackoff
+// RetryOn has the status codes retried, request error codes (like StatusTimeout) and HTTP codes (like 503)
+// A Retry-After header in the response replaces the random wait, also capped by MaxBackoff, or by 30 seconds without it
+// MaxRetryTime is the max time of a call with its retries, default is 2 minutes, a retry that would end its wait after it isn't made
+type RetryPolicy struct {
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	MaxRetryTime time.Duration
+	RetryOn      []int
+}
+
+// Retry-After cap of the policies without MaxBackoff
+const maxRetryAfter = 30 * time.Second
+
+// Max time of a call with its retries of the policies without MaxRetryTime
+const maxRetryTime = 2 * time.Minute
+
+// HTTPAttempt The outcome of one attempt of a call
+// Wait is the time waited before the next attempt
+type HTTPAttempt struct {
+	StatusCode   int
+	Error        string
+	ResponseTime float64
+	Wait         time.Duration
+}
+
+// GetDefaultRetryPolicy Get a policy that retries timeouts, connection resets and overloaded servers 3 times
+func GetDefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+		RetryOn: []int{
+			StatusTimeout,
+			StatusConnectionReset,
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// SetRetryPolicy Set how the request is retried, it wins over the client policy
+func (h HTTPRequest) SetRetryPolicy(policy RetryPolicy) HTTPRequest {
+	h.retryPolicy = &policy
+	return h
+}
+
+// SetRetryPolicy Set how the client calls are retried
+func (c *HTTPClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = &policy
+}
+
+// retryCall Make a call, retrying it while the policy allows
+func (c HTTPClient) retryCall(ctx context.Context, request HTTPRequest, policy RetryPolicy) HTTPResponse {
+	var attempts []HTTPAttempt
+
+	request, err := request.bufferBodyReader()
+
+	if err != nil {
+		return HTTPResponse{Method: request.method, URL: request.url, Error: err.Error(), StatusCode: StatusInvalidRequest}
+	}
+
+	retryTime := policy.MaxRetryTime
+	if retryTime <= 0 {
+		retryTime = maxRetryTime
+	}
+
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		response := c.call(ctx, request)
+
+		attempts = append(attempts, HTTPAttempt{
+			StatusCode:   response.StatusCode,
+			Error:        response.Error,
+			ResponseTime: response.ResponseTime,
+		})
+
+		if attempt >= policy.MaxAttempts || !policy.shouldRetry(response.StatusCode) {
+			response.Attempts = attempt
+			response.AttemptResults = attempts
+
+			return response
+		}
+
+		wait := policy.wait(attempt, response.GetHeader("Retry-After"))
+
+		// The retry would start after the max retry time
+		if time.Since(start)+wait > retryTime {
+			response.Attempts = attempt
+			response.AttemptResults = attempts
+
+			return response
+		}
+
+		attempts[len(attempts)-1].Wait = wait
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			response = contextDoneResponse(request, ctx.Err())
+
+			response.Attempts = attempt
+			response.AttemptResults = attempts
+
+			return response
+		}
+	}
+}
+
+// If a call with the status code should be retried
+func (p RetryPolicy) shouldRetry(statusCode int) bool {
+	for _, code := range p.RetryOn {
+		if code == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Time to wait before the next attempt
+func (p RetryPolicy) wait(attempt int, retryAfterHeader string) time.Duration {
+	if retryAfter, ok := parseRetryAfter(retryAfterHeader, time.Now()); ok {
+		if p.MaxBackoff <= 0 && retryAfter > maxRetryAfter {
+			return maxRetryAfter
+		}
+
+		return p.capBackoff(retryAfter)
+	}
+
+	backoff := p.BaseBackoff
+	// A MaxBackoff of 0 doesn't cap the backoff, the doubling stops before it overflows
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff) && backoff <= math.MaxInt64/2; i++ {
+		backoff *= 2
+	}
+
+	backoff = p.capBackoff(backoff)
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+func (p RetryPolicy) capBackoff(backoff time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return backoff
+}
+
+// Parse a Retry-After header, in seconds or as a http date
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+
+	if err != nil {
+		return 0, false
+	}
+
+	if date.Before(now) {
+		return 0, true
+	}
+
+	return date.Sub(now), true
+}
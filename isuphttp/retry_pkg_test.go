@@ -0,0 +1,83 @@
+package isuphttp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		header        string
+		expectedWait  time.Duration
+		expectedFound bool
+	}{
+		{"", 0, false},
+		{"abc", 0, false},
+		{"-1", 0, false},
+		{"0", 0, true},
+		{"3", 3 * time.Second, true},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{now.Add(-10 * time.Second).Format(http.TimeFormat), 0, true},
+	}
+
+	for _, test := range tests {
+		wait, found := parseRetryAfter(test.header, now)
+
+		assert.Equal(t, test.expectedFound, found, test.header)
+		assert.Equal(t, test.expectedWait, wait, test.header)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	uncapped := RetryPolicy{BaseBackoff: 100 * time.Millisecond}
+
+	var tests = []struct {
+		policy     RetryPolicy
+		attempt    int
+		retryAfter string
+		maxWait    time.Duration
+	}{
+		{policy, 1, "", 100 * time.Millisecond},
+		{policy, 2, "", 200 * time.Millisecond},
+		{policy, 3, "", 400 * time.Millisecond},
+		{policy, 10, "", time.Second},
+		{policy, 1, "60", time.Second},
+		{uncapped, 3, "", 400 * time.Millisecond},
+		{uncapped, 6, "", 3200 * time.Millisecond},
+		{uncapped, 1, "86400", maxRetryAfter},
+	}
+
+	for _, test := range tests {
+		longest := time.Duration(0)
+
+		for i := 0; i < 20; i++ {
+			wait := test.policy.wait(test.attempt, test.retryAfter)
+
+			assert.GreaterOrEqual(t, wait, time.Duration(0))
+			assert.LessOrEqual(t, wait, test.maxWait)
+
+			if wait > longest {
+				longest = wait
+			}
+		}
+
+		// The backoff grows with the attempts
+		assert.Greater(t, longest, test.maxWait/2)
+	}
+
+	assert.Equal(t, time.Second, policy.wait(1, "60"))
+	assert.Equal(t, maxRetryAfter, uncapped.wait(1, "86400"))
+	assert.Equal(t, time.Minute, RetryPolicy{MaxBackoff: time.Hour}.wait(1, "60"))
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.wait(3, ""))
+
+	// The backoff of many attempts doesn't overflow
+	for i := 0; i < 20; i++ {
+		assert.GreaterOrEqual(t, uncapped.wait(200, ""), time.Duration(0))
+	}
+}
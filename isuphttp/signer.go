@@ -0,0 +1,307 @@
+package isuphttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer Sign a request once it is built, with its final method, url, headers and body
+// It runs after the request AuthProvider, so it signs the auth headers too
+type Signer interface {
+	Sign(request *http.Request, body []byte) error
+}
+
+// SetSigner Set the signer of the request, it replaces the client one
+func (h HTTPRequest) SetSigner(signer Signer) HTTPRequest {
+	h.signer = signer
+	return h
+}
+
+// SetSigner Set the signer of the requests without one
+func (c *HTTPClient) SetSigner(signer Signer) {
+	c.signer = signer
+}
+
+// signRequest Sign a built request with its body, the body is read again from GetBody or kept in memory
+func signRequest(signer Signer, request *http.Request) error {
+	var body []byte
+
+	if request.Body != nil && request.Body != http.NoBody {
+		reader := request.Body
+
+		if request.GetBody != nil {
+			var err error
+			if reader, err = request.GetBody(); err != nil {
+				return err
+			}
+		}
+
+		content, err := io.ReadAll(reader)
+		reader.Close()
+
+		if err != nil {
+			return err
+		}
+
+		body = content
+
+		if request.GetBody == nil {
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			request.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+	}
+
+	return signer.Sign(request, body)
+}
+
+// HMACSigner Sign requests with a HMAC-SHA256 of a canonical string
+// SignatureHeader has the hex signature, default is X-Signature
+// TimestampHeader has the unix time of the signature, default is X-Timestamp
+// The default canonical string has a line for the method, the path with the query,
+// the timestamp, each SignedHeaders value as name:value and the hex SHA-256 of the body
+// CanonicalString replaces it and Now replaces the clock, for tests
+type HMACSigner struct {
+	Key             []byte
+	SignatureHeader string
+	TimestampHeader string
+	SignedHeaders   []string
+	CanonicalString func(request *http.Request, timestamp string, bodyHash string) string
+	Now             func() time.Time
+}
+
+// Sign Set the signature and timestamp headers
+func (s HMACSigner) Sign(request *http.Request, body []byte) error {
+	signatureHeader, timestampHeader := s.SignatureHeader, s.TimestampHeader
+
+	if signatureHeader == "" {
+		signatureHeader = "X-Signature"
+	}
+
+	if timestampHeader == "" {
+		timestampHeader = "X-Timestamp"
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	bodyHash := sha256Hex(body)
+
+	request.Header.Set(timestampHeader, timestamp)
+
+	var canonical string
+
+	if s.CanonicalString != nil {
+		canonical = s.CanonicalString(request, timestamp, bodyHash)
+	} else {
+		lines := []string{request.Method, request.URL.RequestURI(), timestamp}
+
+		for _, name := range s.SignedHeaders {
+			lines = append(lines, strings.ToLower(name)+":"+strings.TrimSpace(request.Header.Get(name)))
+		}
+
+		canonical = strings.Join(append(lines, bodyHash), "\n")
+	}
+
+	request.Header.Set(signatureHeader, hex.EncodeToString(hmacSHA256(s.Key, canonical)))
+
+	return nil
+}
+
+// AWS Signature Version 4 values
+const (
+	awsAlgorithm       = "AWS4-HMAC-SHA256"
+	awsTimeFormat      = "20060102T150405Z"
+	awsDateFormat      = "20060102"
+	awsUnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Headers that can be changed on the way and are not signed
+var awsUnsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+}
+
+// AWSSigV4Signer Sign requests with AWS Signature Version 4
+// Every request header is signed, with the host and X-Amz-Date. SessionToken is
+// sent in X-Amz-Security-Token when set. For the s3 service, or when UnsignedPayload
+// is true, the body hash is sent in X-Amz-Content-Sha256. Now replaces the clock, for tests
+type AWSSigV4Signer struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Service         string
+	UnsignedPayload bool
+	Now             func() time.Time
+}
+
+// Sign Set the X-Amz-Date and Authorization headers
+func (s AWSSigV4Signer) Sign(request *http.Request, body []byte) error {
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" || s.Region == "" || s.Service == "" {
+		return fmt.Errorf("aws sigv4: access key, secret key, region and service are required")
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	signTime := now().UTC()
+	amzDate := signTime.Format(awsTimeFormat)
+	scope := strings.Join([]string{signTime.Format(awsDateFormat), s.Region, s.Service, "aws4_request"}, "/")
+
+	payloadHash := sha256Hex(body)
+	if s.UnsignedPayload {
+		payloadHash = awsUnsignedPayload
+	}
+
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	if s.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	if s.Service == "s3" || s.UnsignedPayload {
+		request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := awsCanonicalHeaders(request)
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsCanonicalURI(request.URL, s.Service == "s3"),
+		awsCanonicalQuery(request.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{awsAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), signTime.Format(awsDateFormat))
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, s.AccessKeyID, scope, signedHeaders, signature))
+
+	return nil
+}
+
+// awsCanonicalURI Get the encoded path of a request, encoded twice for services other than s3
+func awsCanonicalURI(requestURL *url.URL, s3 bool) string {
+	path := requestURL.EscapedPath()
+	if s3 {
+		path = requestURL.Path
+	}
+
+	if path == "" {
+		return "/"
+	}
+
+	return awsEscape(path, false)
+}
+
+// awsCanonicalQuery Get the query parameters encoded and sorted by name and value
+func awsCanonicalQuery(query url.Values) string {
+	params := make([]string, 0, len(query))
+
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// awsCanonicalHeaders Get the canonical headers lines and the signed headers names of a request
+func awsCanonicalHeaders(request *http.Request) (string, string) {
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+
+		if awsUnsignedHeaders[name] {
+			continue
+		}
+
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// awsEscape Encode every byte but the unreserved characters, and the slash if it isn't a query value
+func awsEscape(value string, encodeSlash bool) string {
+	var escaped strings.Builder
+
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil)
+}
+
+func sha256Hex(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
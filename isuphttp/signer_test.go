@@ -0,0 +1,146 @@
+package isuphttp_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// AWS Signature Version 4 test suite credentials
+var awsTestSigner = isuphttp.AWSSigV4Signer{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:          "us-east-1",
+	Service:         "service",
+	Now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+}
+
+func TestAWSSigV4TestSuite(t *testing.T) {
+	var tests = []struct {
+		name              string
+		url               string
+		expectedSignature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest(isuphttp.GET, test.url, nil)
+
+		assert.Nil(t, awsTestSigner.Sign(request, nil), test.name)
+		assert.Equal(t, "20150830T123600Z", request.Header.Get("X-Amz-Date"), test.name)
+		assert.Equal(t,
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+test.expectedSignature,
+			request.Header.Get("Authorization"), test.name)
+	}
+}
+
+func TestAWSSigV4Signer(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	signer := awsTestSigner
+	signer.Service = "s3"
+	signer.SessionToken = "session"
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.SetSigner(signer)
+
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.PUT, "https://bucket.s3.amazonaws.com/a file.txt").
+		SetRawBody([]byte("content"), "text/plain"))
+
+	headers := transport.GetCalls()[0].Headers
+
+	assert.Equal(t, "session", headers.Get("X-Amz-Security-Token"))
+	assert.Equal(t, "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", headers.Get("X-Amz-Content-Sha256"))
+	assert.True(t, strings.HasPrefix(headers.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature="))
+
+	signer.UnsignedPayload = true
+	HTTPClient.SetSigner(signer)
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://bucket.s3.amazonaws.com/"))
+
+	assert.Equal(t, "UNSIGNED-PAYLOAD", transport.GetCalls()[1].Headers.Get("X-Amz-Content-Sha256"))
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://bucket.s3.amazonaws.com/").
+		SetSigner(isuphttp.AWSSigV4Signer{AccessKeyID: "AKIDEXAMPLE"}))
+
+	assert.Equal(t, isuphttp.StatusAuthFailed, response.StatusCode)
+	assert.Len(t, transport.GetCalls(), 2)
+}
+
+func TestHMACSigner(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+	now := func() time.Time { return time.Unix(1700000000, 0) }
+
+	sign := func(canonical string) string {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(canonical))
+
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+	HTTPClient.SetSigner(isuphttp.HMACSigner{Key: []byte("secret"), SignedHeaders: []string{"Content-Type", "X-Tenant"}, Now: now})
+
+	// Bodies from a reader are signed and still sent
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.POST, "https://api.local/orders").
+		SetQueryParams(map[string]interface{}{"b": 2, "a": 1}).
+		SetHeaderValue("X-Tenant", "acme").
+		SetBodyReader(strings.NewReader(`{"id":1}`), isuphttp.ApplicationJSON))
+
+	call := transport.GetCalls()[0]
+	bodyHash := sha256.Sum256([]byte(`{"id":1}`))
+	canonical := "POST\n/orders?a=1&b=2\n1700000000\ncontent-type:application/json\nx-tenant:acme\n" + hex.EncodeToString(bodyHash[:])
+
+	assert.Equal(t, "1700000000", call.Headers.Get("X-Timestamp"))
+	assert.Equal(t, sign(canonical), call.Headers.Get("X-Signature"))
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, call.Body)
+
+	// The request signer replaces the client one
+	HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local/orders").
+		SetSigner(isuphttp.HMACSigner{
+			Key:             []byte("secret"),
+			SignatureHeader: "X-Hub-Signature",
+			TimestampHeader: "X-Hub-Time",
+			Now:             now,
+			CanonicalString: func(request *http.Request, timestamp string, bodyHash string) string {
+				return request.URL.Path + "|" + timestamp
+			},
+		}))
+
+	call = transport.GetCalls()[1]
+
+	assert.Equal(t, "1700000000", call.Headers.Get("X-Hub-Time"))
+	assert.Equal(t, sign("/orders|1700000000"), call.Headers.Get("X-Hub-Signature"))
+	assert.Empty(t, call.Headers.Get("X-Signature"))
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign(request *http.Request, body []byte) error {
+	return errors.New("no signing key")
+}
+
+func TestSignerError(t *testing.T) {
+	transport := isuphttp.GetMockTransport()
+
+	HTTPClient := isuphttp.HTTPClient{}
+	HTTPClient.SetTransport(transport)
+
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, "https://api.local").SetSigner(failingSigner{}))
+
+	assert.Equal(t, isuphttp.StatusAuthFailed, response.StatusCode)
+	assert.Equal(t, "no signing key", response.Error)
+	assert.Empty(t, transport.GetCalls())
+}
@@ -2,13 +2,39 @@ package isuphttp
 
 // HTTP status code for request errors
 const (
-	StatusTimeout     = 1 // Request Timeout
-	StatusInvalidCert = 2 // Invalid SSL Certificate
+	StatusTimeout           = 1  // Request Timeout
+	StatusInvalidCert       = 2  // Invalid SSL Certificate
+	StatusCanceled          = 3  // Request Canceled
+	StatusDNSFailure        = 4  // DNS Failure
+	StatusConnectionRefused = 5  // Connection Refused
+	StatusConnectionReset   = 6  // Connection Reset
+	StatusHostUnreachable   = 7  // Host Unreachable
+	StatusUnknownAuthority  = 8  // Unknown Certificate Authority
+	StatusHostnameMismatch  = 9  // Certificate Hostname Mismatch
+	StatusCertExpired       = 10 // Certificate Expired
+	StatusTLSHandshake      = 11 // TLS Handshake Failure
+	StatusInvalidRequest    = 12 // Invalid Request
+	StatusTooManyRedirects  = 13 // Too Many Redirects
+	StatusInsecureRedirect  = 14 // Insecure Redirect
+	StatusAuthFailed        = 15 // Authentication Failed
 )
 
 var statusText = map[int]string{
-	StatusTimeout:     "Request Timeout",
-	StatusInvalidCert: "Invalid SSL Certificate",
+	StatusTimeout:           "Request Timeout",
+	StatusInvalidCert:       "Invalid SSL Certificate",
+	StatusCanceled:          "Request Canceled",
+	StatusDNSFailure:        "DNS Failure",
+	StatusConnectionRefused: "Connection Refused",
+	StatusConnectionReset:   "Connection Reset",
+	StatusHostUnreachable:   "Host Unreachable",
+	StatusUnknownAuthority:  "Unknown Certificate Authority",
+	StatusHostnameMismatch:  "Certificate Hostname Mismatch",
+	StatusCertExpired:       "Certificate Expired",
+	StatusTLSHandshake:      "TLS Handshake Failure",
+	StatusInvalidRequest:    "Invalid Request",
+	StatusTooManyRedirects:  "Too Many Redirects",
+	StatusInsecureRedirect:  "Insecure Redirect",
+	StatusAuthFailed:        "Authentication Failed",
 }
 
 // StatusText returns a text for the HTTP errors status code. It returns the empty
@@ -0,0 +1,144 @@
+package isuphttp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig TLS settings of a call
+// CAPEM and CAFile have PEM certificates of the trusted authorities, like a private CA.
+// When they are set only these authorities are trusted, unless IncludeSystemRoots is true.
+// ClientCertPEM and ClientKeyPEM, or ClientCertFile and ClientKeyFile, are the client
+// certificate sent to mTLS servers. MinVersion, MaxVersion and CipherSuites take the
+// crypto/tls values, like tls.VersionTLS12, CipherSuites only apply to TLS 1.2 and lower.
+// ServerName replaces the host name sent in the SNI and checked in the server certificate.
+// Files are read when the first call with the settings is made, and again after Close
+// or after no call used the settings for the idle connections timeout.
+type TLSConfig struct {
+	CAPEM              []byte
+	CAFile             string
+	IncludeSystemRoots bool
+	ClientCertPEM      []byte
+	ClientKeyPEM       []byte
+	ClientCertFile     string
+	ClientKeyFile      string
+	MinVersion         uint16
+	MaxVersion         uint16
+	CipherSuites       []uint16
+	ServerName         string
+}
+
+// Error of a TLS configuration that can't be used
+var errInvalidTLSConfig = errors.New("invalid tls config")
+
+// SetTLSConfig Set the TLS settings of the request, they replace the client ones
+func (h HTTPRequest) SetTLSConfig(config TLSConfig) HTTPRequest {
+	h.tlsConfig = &config
+	return h
+}
+
+// SetTLSConfig Set the TLS settings of the requests without their own
+func (c *HTTPClient) SetTLSConfig(config TLSConfig) {
+	c.tlsConfig = &config
+}
+
+// fingerprint Get a hash that is the same for equal settings, empty for no settings
+// Keys are hashed, so they are not kept in the transport pool keys
+func (t *TLSConfig) fingerprint() string {
+	if t == nil {
+		return ""
+	}
+
+	hash := sha256.New()
+
+	for _, value := range [][]byte{t.CAPEM, []byte(t.CAFile), t.ClientCertPEM, t.ClientKeyPEM, []byte(t.ClientCertFile), []byte(t.ClientKeyFile), []byte(t.ServerName)} {
+		fmt.Fprintf(hash, "%d:", len(value))
+		hash.Write(value)
+	}
+
+	fmt.Fprintf(hash, "%t %d %d %v", t.IncludeSystemRoots, t.MinVersion, t.MaxVersion, t.CipherSuites)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// clientConfig Get the crypto/tls config of the settings
+func (t *TLSConfig) clientConfig(insecureRequest bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureRequest}
+
+	if t == nil {
+		return config, nil
+	}
+
+	config.MinVersion = t.MinVersion
+	config.MaxVersion = t.MaxVersion
+	config.CipherSuites = append([]uint16(nil), t.CipherSuites...)
+	config.ServerName = t.ServerName
+
+	if len(t.CAPEM) > 0 || t.CAFile != "" {
+		roots := x509.NewCertPool()
+
+		if t.IncludeSystemRoots {
+			systemRoots, err := x509.SystemCertPool()
+
+			if err != nil {
+				return nil, fmt.Errorf("%w: system roots: %v", errInvalidTLSConfig, err)
+			}
+
+			roots = systemRoots
+		}
+
+		caPEM := append([]byte(nil), t.CAPEM...)
+
+		if t.CAFile != "" {
+			content, err := os.ReadFile(t.CAFile)
+
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errInvalidTLSConfig, err)
+			}
+
+			caPEM = append(append(caPEM, '\n'), content...)
+		}
+
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("%w: no certificates in the CA", errInvalidTLSConfig)
+		}
+
+		config.RootCAs = roots
+	}
+
+	certPEM, keyPEM := t.ClientCertPEM, t.ClientKeyPEM
+
+	for _, file := range []struct {
+		path    string
+		content *[]byte
+	}{{t.ClientCertFile, &certPEM}, {t.ClientKeyFile, &keyPEM}} {
+		if file.path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(file.path)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidTLSConfig, err)
+		}
+
+		*file.content = content
+	}
+
+	if len(certPEM) > 0 || len(keyPEM) > 0 {
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate: %v", errInvalidTLSConfig, err)
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,154 @@
+package isuphttp_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/psenna/isup-http-client/isuphttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate A certificate and its key, in PEM
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+// newTestCertificate Create a certificate signed by parent, or self signed if parent is nil
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	return testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "isup test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	serverCert := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "isup.local"},
+		DNSNames:    []string{"isup.local"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &ca)
+
+	clientCert := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "isup client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &ca)
+
+	serverKeyPair, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	assert.Nil(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.certificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %x", r.TLS.PeerCertificates[0].Subject.CommonName, r.TLS.Version)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MaxVersion:   tls.VersionTLS12,
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+
+	os.WriteFile(caFile, ca.certPEM, 0o600)
+	os.WriteFile(certFile, clientCert.certPEM, 0o600)
+	os.WriteFile(keyFile, clientCert.keyPEM, 0o600)
+
+	mTLS := isuphttp.TLSConfig{CAPEM: ca.certPEM, ClientCertPEM: clientCert.certPEM, ClientKeyPEM: clientCert.keyPEM, ServerName: "isup.local"}
+
+	var tests = []struct {
+		config         *isuphttp.TLSConfig
+		expectedStatus int
+		expectedBody   string
+	}{
+		{nil, isuphttp.StatusHostnameMismatch, ""},
+		{&isuphttp.TLSConfig{ServerName: "isup.local"}, isuphttp.StatusUnknownAuthority, ""},
+		{&isuphttp.TLSConfig{CAPEM: ca.certPEM, ClientCertPEM: clientCert.certPEM, ClientKeyPEM: clientCert.keyPEM}, isuphttp.StatusHostnameMismatch, ""},
+		{&mTLS, http.StatusOK, "isup client 303"},
+		{&isuphttp.TLSConfig{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, ServerName: "isup.local", MinVersion: tls.VersionTLS12}, http.StatusOK, "isup client 303"},
+		{&isuphttp.TLSConfig{CAPEM: ca.certPEM, ClientCertPEM: clientCert.certPEM, ClientKeyPEM: clientCert.keyPEM, ServerName: "isup.local", MinVersion: tls.VersionTLS13}, isuphttp.StatusTLSHandshake, ""},
+		{&isuphttp.TLSConfig{CAPEM: ca.certPEM, ClientKeyPEM: clientCert.keyPEM}, isuphttp.StatusInvalidRequest, ""},
+	}
+
+	HTTPClient := isuphttp.HTTPClient{}
+	defer HTTPClient.Close()
+
+	for index, test := range tests {
+		request := isuphttp.GetHTTPRequest(isuphttp.GET, server.URL)
+		if test.config != nil {
+			request = request.SetTLSConfig(*test.config)
+		}
+
+		response := HTTPClient.HTTPCall(request)
+
+		assert.Equal(t, test.expectedStatus, response.StatusCode, index)
+		assert.Equal(t, test.expectedBody, response.Body, index)
+	}
+
+	// Without a client certificate the server refuses the handshake
+	response := HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetTLSConfig(isuphttp.TLSConfig{CAPEM: ca.certPEM, ServerName: "isup.local"}))
+	assert.NotEqual(t, http.StatusOK, response.StatusCode)
+	assert.NotEmpty(t, response.Error)
+
+	// The client settings are used by requests without their own
+	HTTPClient.SetTLSConfig(mTLS)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = HTTPClient.HTTPCall(isuphttp.GetHTTPRequest(isuphttp.GET, server.URL).SetTLSConfig(isuphttp.TLSConfig{ServerName: "isup.local"}))
+	assert.Equal(t, isuphttp.StatusUnknownAuthority, response.StatusCode)
+}
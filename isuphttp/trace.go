@@ -0,0 +1,67 @@
+package isuphttp
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestTrace Collect the time of each phase of a http call
+type requestTrace struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+// clientTrace Get the httptrace hooks that fill the trace
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			// Dual stack dials may start more than one connection, keep the first
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone:          func(string, string, error) { t.mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+}
+
+func (t *requestTrace) mark(moment *time.Time) {
+	t.mu.Lock()
+	*moment = time.Now()
+	t.mu.Unlock()
+}
+
+// fill Set the phases durations in the response
+// bodyRead is the moment the response body was fully read
+func (t *requestTrace) fill(response *HTTPResponse, bodyRead time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response.DNSLookup = phaseDuration(t.dnsStart, t.dnsDone)
+	response.Connect = phaseDuration(t.connectStart, t.connectDone)
+	response.TLSHandshake = phaseDuration(t.tlsStart, t.tlsDone)
+	response.TimeToFirstByte = phaseDuration(t.wroteRequest, t.firstByte)
+	response.ContentTransfer = phaseDuration(t.firstByte, bodyRead)
+}
+
+// Duration between two moments, zero if the phase didn't happen
+func phaseDuration(start time.Time, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
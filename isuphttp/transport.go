@@ -0,0 +1,179 @@
+package isuphttp
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default idle connections limits of the pooled transports
+const (
+	maxIdleConns        = 100
+	maxIdleConnsPerHost = 10
+	idleConnTimeout     = 90 * time.Second
+	keepAlive           = 30 * time.Second
+)
+
+// Requests timeouts are rounded up to one of these classes, so requests with
+// close timeouts share the same transport. The exact request timeout is still
+// enforced by the http.Client.
+var timeOutClasses = []int{500, 1000, timeOut, 5000, 10000, 30000, maxTimeout}
+
+// Pool used by clients without their own idle connections settings
+var defaultTransportPool = newTransportPool(maxIdleConns, maxIdleConnsPerHost, idleConnTimeout)
+
+// transportKey Settings that need a different transport
+type transportKey struct {
+	insecureRequest bool
+	timeOutClass    int
+	tlsConfig       string
+}
+
+// transportPool Reusable transports, keyed by the request settings
+// Transports are counted by the calls using them, so a closed pool only closes the
+// unused ones at once and the others when their last call ends. Transports not used
+// for the idle timeout are dropped, with their TLS settings.
+type transportPool struct {
+	mu                  sync.Mutex
+	transports          map[transportKey]*pooledTransport
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+// pooledTransport A transport of the pool and the calls using it
+type pooledTransport struct {
+	transport *http.Transport
+	calls     int
+	lastUsed  time.Time
+	dropped   bool
+}
+
+func newTransportPool(maxIdle int, maxIdlePerHost int, idleTimeout time.Duration) *transportPool {
+	return &transportPool{
+		transports:          make(map[transportKey]*pooledTransport),
+		maxIdleConns:        maxIdle,
+		maxIdleConnsPerHost: maxIdlePerHost,
+		idleConnTimeout:     idleTimeout,
+	}
+}
+
+// get Get the transport for a request, creating it if needed, and the function
+// that releases it when the call ends
+// It fails if the request TLS settings can't be used
+func (p *transportPool) get(request HTTPRequest) (*http.Transport, func(), error) {
+	key := transportKey{
+		insecureRequest: request.GetInsecureRequest(),
+		timeOutClass:    timeOutClass(request.GetTimeOut()),
+		tlsConfig:       request.tlsConfig.fingerprint(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dropIdle(time.Now())
+
+	pooled, ok := p.transports[key]
+
+	if !ok {
+		tr, err := p.newTransport(request, key)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pooled = &pooledTransport{transport: tr, lastUsed: time.Now()}
+		p.transports[key] = pooled
+	}
+
+	pooled.calls++
+
+	release := func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		pooled.calls--
+		pooled.lastUsed = time.Now()
+
+		if pooled.dropped && pooled.calls == 0 {
+			pooled.transport.CloseIdleConnections()
+		}
+	}
+
+	return pooled.transport, release, nil
+}
+
+// newTransport Create the transport of the requests with a key
+func (p *transportPool) newTransport(request HTTPRequest, key transportKey) (*http.Transport, error) {
+	tlsConfig, err := request.tlsConfig.clientConfig(key.insecureRequest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(key.timeOutClass) * time.Millisecond
+
+	return &http.Transport{
+		TLSClientConfig: tlsConfig,
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: keepAlive,
+		}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: timeout,
+		MaxIdleConns:          p.maxIdleConns,
+		MaxIdleConnsPerHost:   p.maxIdleConnsPerHost,
+		IdleConnTimeout:       p.idleConnTimeout,
+	}, nil
+}
+
+// dropIdle Drop the transports not used by any call for the idle timeout, their connections are already closed
+func (p *transportPool) dropIdle(now time.Time) {
+	idleTimeout := p.idleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = idleConnTimeout
+	}
+
+	for key, pooled := range p.transports {
+		if pooled.calls == 0 && now.Sub(pooled.lastUsed) > idleTimeout {
+			p.drop(key, pooled)
+		}
+	}
+}
+
+// drop Remove a transport from the pool, it is closed when its last call ends
+func (p *transportPool) drop(key transportKey, pooled *pooledTransport) {
+	delete(p.transports, key)
+	pooled.dropped = true
+
+	if pooled.calls == 0 {
+		pooled.transport.CloseIdleConnections()
+	}
+}
+
+// close Empty the pool and close the idle connections of its transports, the ones in use when their calls end
+func (p *transportPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, pooled := range p.transports {
+		p.drop(key, pooled)
+	}
+}
+
+// Round a timeout up to its class, 0 means no timeout
+func timeOutClass(timeOut int) int {
+	if timeOut <= 0 {
+		return 0
+	}
+
+	for _, class := range timeOutClasses {
+		if timeOut <= class {
+			return class
+		}
+	}
+
+	return timeOut
+}
@@ -0,0 +1,116 @@
+package isuphttp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeOutClass(t *testing.T) {
+	var tests = []struct {
+		timeOut       int
+		expectedClass int
+	}{
+		{0, 0},
+		{50, 500},
+		{500, 500},
+		{501, 1000},
+		{2000, 2000},
+		{15000, 30000},
+		{60000, 60000},
+		{70000, 70000},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expectedClass, timeOutClass(test.timeOut))
+	}
+}
+
+func TestTransportPoolReuse(t *testing.T) {
+	pool := newTransportPool(10, 2, 0)
+
+	get := func(request HTTPRequest) *http.Transport {
+		tr, release, err := pool.get(request)
+		assert.Nil(t, err)
+		release()
+
+		return tr
+	}
+
+	request := GetHTTPRequest(GET, "localhost:8080/api")
+
+	first := get(request)
+
+	assert.Same(t, first, get(request.SetTimeOut(1500)))
+	assert.NotSame(t, first, get(request.SetTimeOut(5000)))
+	assert.NotSame(t, first, get(request.SetInsecureRequest(true)))
+	assert.Equal(t, 2, first.MaxIdleConnsPerHost)
+
+	pool.close()
+
+	assert.NotSame(t, first, get(request))
+}
+
+func TestTransportPoolTLSConfig(t *testing.T) {
+	pool := newTransportPool(10, 2, 0)
+
+	request := GetHTTPRequest(GET, "localhost:8080/api")
+	config := TLSConfig{MinVersion: tls.VersionTLS13, ServerName: "isup.local", CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}}
+
+	first, _, err := pool.get(request.SetTLSConfig(config))
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), first.TLSClientConfig.MinVersion)
+	assert.Equal(t, "isup.local", first.TLSClientConfig.ServerName)
+
+	same, _, _ := pool.get(request.SetTLSConfig(TLSConfig{MinVersion: tls.VersionTLS13, ServerName: "isup.local", CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}}))
+	assert.Same(t, first, same)
+
+	plain, _, _ := pool.get(request)
+	assert.NotSame(t, first, plain)
+
+	config.ServerName = "other.local"
+	other, _, _ := pool.get(request.SetTLSConfig(config))
+	assert.NotSame(t, first, other)
+
+	_, _, err = pool.get(request.SetTLSConfig(TLSConfig{CAPEM: []byte("not a certificate")}))
+	assert.ErrorIs(t, err, errInvalidTLSConfig)
+
+	_, _, err = pool.get(request.SetTLSConfig(TLSConfig{ClientCertPEM: []byte("not a certificate")}))
+	assert.ErrorContains(t, err, "invalid tls config: client certificate")
+
+	_, _, err = pool.get(request.SetTLSConfig(TLSConfig{CAFile: "testdata/missing.pem"}))
+	assert.ErrorIs(t, err, errInvalidTLSConfig)
+}
+
+func TestTransportPoolRelease(t *testing.T) {
+	pool := newTransportPool(10, 2, 50*time.Millisecond)
+
+	request := GetHTTPRequest(GET, "localhost:8080/api")
+
+	// A transport in use is kept by the call when the pool is closed
+	inUse, release, err := pool.get(request)
+	assert.Nil(t, err)
+
+	pool.close()
+	assert.Empty(t, pool.transports)
+
+	other, releaseOther, _ := pool.get(request)
+	assert.NotSame(t, inUse, other)
+
+	release()
+	releaseOther()
+
+	// A transport unused for the idle timeout is dropped with its TLS settings
+	time.Sleep(100 * time.Millisecond)
+
+	pool.get(request.SetTLSConfig(TLSConfig{ServerName: "isup.local"}))
+
+	assert.Len(t, pool.transports, 1)
+
+	for key := range pool.transports {
+		assert.NotEmpty(t, key.tlsConfig)
+	}
+}